@@ -2,11 +2,20 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
 	"adapter/internal/config"
+	permissionsDomain "adapter/internal/domain/permissions"
 	registryDomain "adapter/internal/domain/registry_sync"
 	catalogPorts "adapter/internal/ports/catalog_sync"
+	idempotencyPorts "adapter/internal/ports/idempotency"
+	permissionsPorts "adapter/internal/ports/permissions"
 	registryPorts "adapter/internal/ports/registry_sync"
 	"adapter/internal/shared/database"
 	"adapter/internal/shared/log"
@@ -19,7 +28,10 @@ func main() {
 	// Define a command-line flag to trigger the job immediately
 	runNow := flag.Bool("run-now", false, "Run the job once immediately and exit")
 	flag.Parse()
-	ctx := context.Background()
+
+	// Cancel in-flight work on SIGINT/SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	// Load environment variables
 	if err := godotenv.Load(); err != nil {
@@ -42,21 +54,29 @@ func main() {
 	}
 
 	// Create repository and service
-	sellerRepo := catalogPorts.NewGormRepository(db)
-	ondcService := registryDomain.NewONDCService(sellerRepo, cfg)
+	sellerRepo := catalogPorts.NewGormRepository(db).WithBatchSize(cfg.RegistrySyncBatchSize)
+	permissionsService := permissionsDomain.NewPermissionsService(permissionsPorts.NewGormRepository(db).WithBatchSize(cfg.RegistrySyncBatchSize))
+	syncRunRepo := registryPorts.NewGormRepository(db)
+	lookupArchive, err := registryPorts.NewLookupArchive(cfg.RegistryArchiveMode, cfg.RegistryArchiveDir, db)
+	if err != nil {
+		log.Fatal(ctx, err, "Failed to configure registry response archive")
+	}
+	ondcService := registryDomain.NewONDCService(sellerRepo, syncRunRepo, syncRunRepo, syncRunRepo, lookupArchive, syncRunRepo, permissionsService, cfg)
+	idempotencyRepo := idempotencyPorts.NewGormRepository(db)
 
 	// If the -run-now flag is provided, run the job once and exit
 	if *runNow {
 		log.Info(ctx, "Starting ONDC seller lookup job manually...")
-		_, err := ondcService.SyncRegistry(registryPorts.SyncRegistryRequest{
-			RegistryEnv: cfg.RegistryEnv,
-			Domains:     cfg.Domains,
-		})
-		if err != nil {
-			log.Error(ctx, err, "ONDC seller lookup cron job failed")
-		} else {
-			log.Info(ctx, "ONDC seller lookup cron job completed successfully.")
+		var wg sync.WaitGroup
+		for _, registry := range ondcService.Registries() {
+			wg.Add(1)
+			go func(registry config.RegistryEnvConfig) {
+				defer wg.Done()
+				runRegistrySync(ctx, ondcService, registry)
+			}(registry)
 		}
+		wg.Wait()
+		ondcService.Close()
 		return
 	}
 
@@ -64,24 +84,75 @@ func main() {
 	// Initialize cron job
 	c := cron.New()
 
-	// Schedule the job to run every 6 hours
-	c.AddFunc("@every 6h", func() {
-		log.Info(ctx, "Starting ONDC seller lookup cron job...")
-		_, err := ondcService.SyncRegistry(registryPorts.SyncRegistryRequest{
-			RegistryEnv: cfg.RegistryEnv,
-			Domains:     cfg.Domains,
+	// Schedule the job to run every 6 hours for every configured registry env
+	for _, registry := range ondcService.Registries() {
+		c.AddFunc("@every 6h", func() {
+			log.Infof(ctx, "Starting ONDC seller lookup cron job for %s...", registry.Name)
+			runRegistrySync(ctx, ondcService, registry)
 		})
+	}
+
+	// Deactivate sellers whose registry validity has ended every hour
+	c.AddFunc("@every 1h", func() {
+		expired, err := ondcService.ExpireSellers(ctx)
 		if err != nil {
-			log.Error(ctx, err, "ONDC seller lookup cron job failed")
-		} else {
-			log.Info(ctx, "ONDC seller lookup cron job completed successfully.")
+			log.Error(ctx, err, "Failed to deactivate expired sellers")
 		}
+		log.Infof(ctx, "Deactivated %d sellers with expired registry validity", expired)
+	})
 
+	// Purge expired idempotency keys every hour
+	c.AddFunc("@every 1h", func() {
+		deleted, err := idempotencyRepo.DeleteExpiredIdempotencyRecords(time.Now())
+		if err != nil {
+			log.Error(ctx, err, "Failed to purge expired idempotency keys")
+			return
+		}
+		log.Infof(ctx, "Purged %d expired idempotency keys", deleted)
 	})
 
 	log.Info(ctx, "Starting cron scheduler...")
 	c.Start()
 
-	// Keep the application running
-	select {}
+	// Keep the application running until a shutdown signal arrives
+	<-ctx.Done()
+	log.Info(context.Background(), "Shutting down cron scheduler...")
+	ondcService.Close()
+	<-c.Stop().Done()
+	log.Info(context.Background(), "Cron scheduler stopped")
+}
+
+// runRegistrySync queues a registry sync job, the same way the internal API
+// does, and waits for it to finish.
+func runRegistrySync(ctx context.Context, ondcService *registryDomain.ONDCService, registry config.RegistryEnvConfig) {
+	job, err := ondcService.StartSyncJob(ctx, registryPorts.SyncRegistryRequest{
+		RegistryEnv:   registry.Name,
+		Domains:       registry.Domains,
+		TriggerSource: registryPorts.TriggerSourceCron,
+	})
+	var alreadyRunning *registryDomain.SyncAlreadyRunningError
+	if errors.As(err, &alreadyRunning) {
+		log.Warnf(ctx, "Skipping ONDC seller lookup job: %v", err)
+		return
+	}
+	if err != nil {
+		log.Error(ctx, err, "Failed to queue ONDC seller lookup job")
+		return
+	}
+	log.Infof(ctx, "ONDC seller lookup job %s queued for %s", job.JobID, registry.Name)
+
+	// On shutdown the job is cancelled by ondcService.Close, which waits for it
+	// to record its outcome
+	result, err := ondcService.WaitSyncJob(ctx, job.JobID)
+	if err != nil {
+		log.Error(ctx, err, "ONDC seller lookup cron job failed")
+		return
+	}
+
+	switch registryPorts.SyncRunStatus(result.Status) {
+	case registryPorts.SyncRunStatusCompleted:
+		log.Info(ctx, "ONDC seller lookup cron job completed successfully.")
+	default:
+		log.Warnf(ctx, "ONDC seller lookup job %s finished with status %s", job.JobID, result.Status)
+	}
 }
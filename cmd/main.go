@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"net"
 	"os"
 	"os/signal"
 	"syscall"
@@ -10,8 +11,14 @@ import (
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
 
 	"adapter/internal/config/di"
+	"adapter/internal/grpc/permissionspb"
+	"adapter/internal/shared/crypto"
 	appError "adapter/internal/shared/error"
 	logger "adapter/internal/shared/log"
 	"adapter/internal/shared/middleware"
@@ -41,25 +48,62 @@ func main() {
 
 	// routes
 	routes := app.Group("/v1")
-	routes.Post("/permissions", container.PermissionsHandler.UpdatePermissions)
-	routes.Post("/permissions/query", container.PermissionsHandler.QueryPermissions)
+	routes.Post("/permissions", middleware.IdempotencyMiddleware(middleware.IdempotencyConfig{
+		Repo:          container.IdempotencyRepo,
+		TTL:           container.Config.IdempotencyKeyTTL,
+		InProgressTTL: container.Config.IdempotencyInProgressTTL,
+		CallerHeader:  container.Config.APIKeyHeader,
+	}), container.PermissionsHandler.UpdatePermissions)
+	if container.Config.ONDCAuthEnabled {
+		// Signed ONDC routes; mount further network-facing routes on this group
+		signed := routes.Group("/permissions/query", middleware.ONDCSignatureAuthMiddleware(ondcSignatureConfig(container)))
+		signed.Post("", container.PermissionsHandler.QueryPermissions)
+	} else {
+		routes.Post("/permissions/query", container.PermissionsHandler.QueryPermissions)
+	}
+	routes.Get("/policy-templates", container.PermissionsHandler.ListPolicyTemplates)
+	routes.Get("/policy-templates/:name", container.PermissionsHandler.GetPolicyTemplate)
+	routes.Put("/policy-templates/:name", container.PermissionsHandler.SavePolicyTemplate)
+	routes.Delete("/policy-templates/:name", container.PermissionsHandler.DeletePolicyTemplate)
 	routes.Get("/catalog-sync/sellers/:seller_id", container.CatalogSyncHandler.GetSyncStatus)
 	routes.Get("/catalog-sync/pending", container.CatalogSyncHandler.GetPendingCatalogSyncSellers)
+	routes.Get("/subscribers/:id/keys", container.RegistrySyncHandler.GetSubscriberKeys)
+	routes.Get("/sellers/expiring", container.CatalogSyncHandler.GetExpiringSellers)
+	routes.Get("/sellers/:seller_id/changes", container.CatalogSyncHandler.GetSellerChanges)
+	routes.Get("/sellers/:seller_id/locations", container.CatalogSyncHandler.GetSellerLocations)
+	routes.Get("/sellers/city-coverage", container.CatalogSyncHandler.GetCityCoverage)
 
 	// Internal routes (nested under /v1)
 	internal := routes.Group("/internal")
 	internal.Post("/registry-sync", container.RegistrySyncHandler.SyncRegistry)
+	internal.Get("/registry-sync/jobs/:job_id", container.RegistrySyncHandler.GetSyncJob)
+	internal.Post("/registry-sync/jobs/:job_id/cancel", container.RegistrySyncHandler.CancelSyncJob)
+	internal.Get("/registry-sync/runs", container.RegistrySyncHandler.ListSyncRuns)
+	internal.Get("/registry-sync/runs/:run_id", container.RegistrySyncHandler.GetSyncRun)
+	internal.Get("/registry-sync/domains", container.RegistrySyncHandler.GetDomainSyncStatuses)
+	internal.Get("/registry-sync/held-deactivations", container.RegistrySyncHandler.ListHeldDeactivations)
+	internal.Get("/registry-sync/held-deactivations/:hold_id", container.RegistrySyncHandler.GetHeldDeactivation)
+	internal.Post("/registry-sync/held-deactivations/:hold_id/approve", container.RegistrySyncHandler.ApproveHeldDeactivation)
+	internal.Post("/registry-sync/held-deactivations/:hold_id/discard", container.RegistrySyncHandler.DiscardHeldDeactivation)
 
 	port := container.Config.Port
 
 	fmt.Printf("\n🚀 Starting %s server\n", container.Config.OtelService)
 	fmt.Printf("   Port: %s\n", port)
+	if container.Config.GRPCPort != "" {
+		fmt.Printf("   gRPC: ✅ Enabled (Port: %s)\n", container.Config.GRPCPort)
+	} else {
+		fmt.Printf("   gRPC: ⚠️  Disabled (Set GRPC_PORT to enable)\n")
+	}
 	fmt.Printf("   Logging: ✅ Enabled (Application-level logging active)\n")
 	if container.Config.OtelURL != "" {
 		fmt.Printf("   Tracing: ✅ Enabled (OTEL endpoint: %s)\n", container.Config.OtelURL)
 	} else {
 		fmt.Printf("   Tracing: ⚠️  Disabled (Set OTEL_URL to enable)\n")
 	}
+	if container.Config.ONDCAuthEnabled {
+		fmt.Printf("   ONDC Auth: ✅ Enabled (Signed requests required for permissions query over HTTP and gRPC)\n")
+	}
 	fmt.Printf("   Health Check: http://localhost:%s/health\n", port)
 	fmt.Printf("\n")
 
@@ -71,6 +115,23 @@ func main() {
 		}
 	}()
 
+	var grpcServer *grpc.Server
+	var healthServer *health.Server
+	if grpcPort := container.Config.GRPCPort; grpcPort != "" {
+		grpcServer, healthServer = newGRPCServer(container)
+		lis, err := net.Listen("tcp", ":"+grpcPort)
+		if err != nil {
+			logger.Fatal(ctx, err, "Error starting gRPC listener")
+		}
+
+		logger.Infof(ctx, "Starting gRPC server on port %s", grpcPort)
+		go func() {
+			if err := grpcServer.Serve(lis); err != nil {
+				logger.Fatal(ctx, err, "Error starting gRPC server")
+			}
+		}()
+	}
+
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 
@@ -80,6 +141,20 @@ func main() {
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
 
+	if grpcServer != nil {
+		healthServer.Shutdown()
+		stopped := make(chan struct{})
+		go func() {
+			grpcServer.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-shutdownCtx.Done():
+			grpcServer.Stop()
+		}
+	}
+
 	if err := container.Shutdown(shutdownCtx); err != nil {
 		logger.Error(ctx, err, "Error during container shutdown")
 	}
@@ -90,3 +165,36 @@ func main() {
 		logger.Info(ctx, "Server shutdown complete")
 	}
 }
+
+func newGRPCServer(container *di.Container) (*grpc.Server, *health.Server) {
+	unary := []grpc.UnaryServerInterceptor{middleware.UnaryRecoveryInterceptor(), middleware.UnaryLoggingInterceptor()}
+	stream := []grpc.StreamServerInterceptor{middleware.StreamRecoveryInterceptor(), middleware.StreamLoggingInterceptor()}
+	if container.Config.ONDCAuthEnabled {
+		// Same signature checks as the signed HTTP routes
+		signatureConfig := ondcSignatureConfig(container)
+		unary = append(unary, middleware.UnaryONDCSignatureInterceptor(signatureConfig))
+		stream = append(stream, middleware.StreamONDCSignatureInterceptor(signatureConfig))
+	}
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(unary...), grpc.ChainStreamInterceptor(stream...))
+
+	permissionspb.RegisterPermissionsServiceServer(server, container.PermissionsGRPC)
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	healthServer.SetServingStatus(permissionspb.PermissionsService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(server, healthServer)
+
+	reflection.Register(server)
+
+	return server, healthServer
+}
+
+func ondcSignatureConfig(container *di.Container) middleware.ONDCSignatureConfig {
+	return middleware.ONDCSignatureConfig{
+		Resolver:                container.ONDCKeyResolver,
+		Crypto:                  crypto.NewONDCCrypto(),
+		Realm:                   container.Config.SubscriberID,
+		RequireGatewaySignature: container.Config.ONDCAuthRequireGatewaySignature,
+		ClockSkew:               container.Config.ONDCAuthClockSkew,
+	}
+}
@@ -0,0 +1,89 @@
+// Command mockregistry serves the ONDC registry lookup API from fixture files
+// for local development of the registry sync:
+//
+//	go run ./cmd/mockregistry -verify \
+//		-fixtures internal/mockregistry/testdata/registry.json,internal/mockregistry/testdata/registry-next.json
+//
+// Point the adapter at it with REGISTRY_URL=http://localhost:8089/v2.0/lookup,
+// or /lookup for the legacy response format. The fixtures register the
+// adapter's default subscriber key, so signed lookups verify out of the box.
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"adapter/internal/mockregistry"
+	"adapter/internal/shared/log"
+)
+
+func main() {
+	addr := flag.String("addr", ":8089", "address to listen on")
+	fixtures := flag.String("fixtures", "internal/mockregistry/testdata/registry.json", "comma-separated fixture files, one per generation")
+	advanceEvery := flag.Int("advance-every", 0, "move to the next generation after this many lookups (0: only via POST /mock/advance)")
+	churn := flag.Float64("churn", 0, "share (0-1) of subscribers dropped from each generation after the first")
+	seed := flag.Int64("seed", 1, "seed for churn, jitter and random errors")
+	verify := flag.Bool("verify", false, "reject lookups without a valid signature")
+	keys := flag.String("keys", "", "extra signing keys as comma-separated subscriber_id|ukId=public_key")
+	latency := flag.Duration("latency", 0, "delay added to every lookup")
+	jitter := flag.Duration("jitter", 0, "random extra delay of up to this much")
+	failFirst := flag.Int("fail-first", 0, "fail this many lookups before answering")
+	errorRate := flag.Float64("error-rate", 0, "share (0-1) of lookups that fail")
+	errorStatus := flag.Int("error-status", 503, "HTTP status of failed lookups")
+	retryAfter := flag.Duration("retry-after", 0, "Retry-After sent with failed lookups")
+	pageSize := flag.Int("page-size", 0, "entries per response page (0: no pagination)")
+	flag.Parse()
+
+	log.InitLogger(log.Config{Level: "info", Destinations: []log.Destination{{Type: "stdout"}}})
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	cfg := mockregistry.Config{
+		Fixtures:         strings.Split(*fixtures, ","),
+		AdvanceEvery:     *advanceEvery,
+		Churn:            *churn,
+		Seed:             *seed,
+		VerifySignatures: *verify,
+		Keys:             make(map[string]string),
+		Latency:          *latency,
+		LatencyJitter:    *jitter,
+		FailFirst:        *failFirst,
+		ErrorRate:        *errorRate,
+		ErrorStatus:      *errorStatus,
+		RetryAfter:       *retryAfter,
+		PageSize:         *pageSize,
+	}
+	if *keys != "" {
+		for _, pair := range strings.Split(*keys, ",") {
+			keyID, publicKey, ok := strings.Cut(pair, "=")
+			if !ok {
+				log.Errorf(ctx, nil, "Invalid -keys entry %q, expected subscriber_id|ukId=public_key", pair)
+				os.Exit(1)
+			}
+			cfg.Keys[keyID] = publicKey
+		}
+	}
+
+	srv, err := mockregistry.New(cfg)
+	if err != nil {
+		log.Error(ctx, err, "Failed to load mock registry")
+		os.Exit(1)
+	}
+
+	go func() {
+		<-ctx.Done()
+		if err := srv.Shutdown(); err != nil {
+			log.Error(ctx, err, "Failed to shut down mock registry")
+		}
+	}()
+
+	log.Infof(ctx, "Mock registry listening on %s with %d fixture generation(s)", *addr, len(cfg.Fixtures))
+	if err := srv.Listen(*addr); err != nil {
+		log.Error(ctx, err, "Mock registry stopped")
+		os.Exit(1)
+	}
+}
@@ -2,6 +2,9 @@ package config
 
 import (
 	"fmt"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/kelseyhightower/envconfig"
@@ -11,6 +14,7 @@ type Config struct {
 	DatabaseURL  string   `envconfig:"DATABASE_URL" required:"true"`
 	RedisURL     string   `envconfig:"REDIS_URL" required:"true"`
 	Port         string   `envconfig:"PORT" default:"8080"`
+	GRPCPort     string   `envconfig:"GRPC_PORT"`
 	LogLevel     string   `envconfig:"LOG_LEVEL" default:"info"`
 	APIKeyHeader string   `envconfig:"API_KEY_HEADER" default:"X-API-Key"`
 	OtelURL      string   `envconfig:"OTEL_URL"`
@@ -21,6 +25,91 @@ type Config struct {
 	SubscriberID string   `envconfig:"SUBSCRIBER_ID" default:"saleor-preprod.bharatvyapaar.com"`
 	UniqueKeyID  string   `envconfig:"UNIQUE_KEY_ID" default:"4a47f723-69ca-48fb-89e9-4d62c13d51b5"`
 	RegistryEnv  string   `envconfig:"REGISTRY_ENV" default:"preprod"`
+
+	// Default registry lookup criteria, overridable per env with
+	// REGISTRY_<ENV>_COUNTRY, _CITY and _TYPE
+	RegistryLookupCountry string `envconfig:"REGISTRY_LOOKUP_COUNTRY" default:"IND"`
+	RegistryLookupCity    string `envconfig:"REGISTRY_LOOKUP_CITY"`
+	RegistryLookupType    string `envconfig:"REGISTRY_LOOKUP_TYPE" default:"BPP"`
+
+	// RegistryResponseFormat selects how lookup responses are parsed: legacy
+	// (flat subscribers), v2 (nested network participants) or auto, which
+	// detects the format per entry. Overridable per env with
+	// REGISTRY_<ENV>_RESPONSE_FORMAT.
+	RegistryResponseFormat string `envconfig:"REGISTRY_RESPONSE_FORMAT" default:"auto"`
+
+	// RegistryEnvs lists every registry env that can be synced. Each env reads
+	// its registry settings from REGISTRY_<ENV>_URL, _SUBSCRIBER_ID,
+	// _UNIQUE_KEY_ID, _PRIVATE_KEY and _DOMAINS; the env named by REGISTRY_ENV
+	// falls back to the settings above.
+	RegistryEnvs []string                     `envconfig:"REGISTRY_ENVS"`
+	Registries   map[string]RegistryEnvConfig `ignored:"true"`
+
+	// RegistryStatusLifecycle maps a registry subscriber status to the seller
+	// lifecycle: active, pending or inactive. Unmapped statuses are inactive.
+	RegistryStatusLifecycle map[string]string `envconfig:"REGISTRY_STATUS_LIFECYCLE" default:"SUBSCRIBED:active,INITIATED:pending,UNDER_SUBSCRIPTION:pending,INVALID_SSL:inactive,UNSUBSCRIBED:inactive"`
+
+	RegistrySyncConcurrency int           `envconfig:"REGISTRY_SYNC_CONCURRENCY" default:"3"`
+	RegistrySyncLockTTL     time.Duration `envconfig:"REGISTRY_SYNC_LOCK_TTL" default:"2m"`
+	// RegistrySyncBatchSize is the number of rows, sellers or template
+	// policies, written per statement during a sync.
+	RegistrySyncBatchSize int `envconfig:"REGISTRY_SYNC_BATCH_SIZE" default:"1000"`
+
+	// Registry client: only retryable failures (network errors, 408, 429 and
+	// 5xx) are retried, with exponential backoff and full jitter. After
+	// RegistryBreakerFailureThreshold consecutive failures the client fails
+	// fast for RegistryBreakerOpenTimeout before probing the registry again.
+	RegistryHTTPTimeout             time.Duration `envconfig:"REGISTRY_HTTP_TIMEOUT" default:"30s"`
+	RegistryRetryMaxAttempts        int           `envconfig:"REGISTRY_RETRY_MAX_ATTEMPTS" default:"4"`
+	RegistryRetryBaseDelay          time.Duration `envconfig:"REGISTRY_RETRY_BASE_DELAY" default:"500ms"`
+	RegistryRetryMaxDelay           time.Duration `envconfig:"REGISTRY_RETRY_MAX_DELAY" default:"10s"`
+	RegistryBreakerFailureThreshold int           `envconfig:"REGISTRY_BREAKER_FAILURE_THRESHOLD" default:"5"`
+	RegistryBreakerOpenTimeout      time.Duration `envconfig:"REGISTRY_BREAKER_OPEN_TIMEOUT" default:"30s"`
+
+	// RegistryArchiveMode keeps each raw registry lookup response, gzipped,
+	// for replaying a run offline: off, disk (under RegistryArchiveDir) or
+	// postgres.
+	RegistryArchiveMode string `envconfig:"REGISTRY_ARCHIVE_MODE" default:"off"`
+	RegistryArchiveDir  string `envconfig:"REGISTRY_ARCHIVE_DIR" default:"data/registry-archive"`
+
+	// A sync holds its deactivations for manual approval instead of applying
+	// them when they exceed either threshold: more than MaxCount sellers, or
+	// more than MaxPercent of the domain's active sellers. Zero disables a
+	// threshold.
+	RegistryDeactivationMaxCount   int     `envconfig:"REGISTRY_DEACTIVATION_MAX_COUNT" default:"100"`
+	RegistryDeactivationMaxPercent float64 `envconfig:"REGISTRY_DEACTIVATION_MAX_PERCENT" default:"20"`
+
+	IdempotencyKeyTTL time.Duration `envconfig:"IDEMPOTENCY_KEY_TTL" default:"24h"`
+	// IdempotencyInProgressTTL is how long a request may hold an
+	// Idempotency-Key before a retry can take it over
+	IdempotencyInProgressTTL time.Duration `envconfig:"IDEMPOTENCY_IN_PROGRESS_TTL" default:"1m"`
+
+	ONDCAuthEnabled                 bool          `envconfig:"ONDC_AUTH_ENABLED" default:"false"`
+	ONDCAuthKeyCacheTTL             time.Duration `envconfig:"ONDC_AUTH_KEY_CACHE_TTL" default:"5m"`
+	// ONDCAuthNegativeCacheTTL is how long a key that could not be resolved
+	// is remembered before the registry is asked again
+	ONDCAuthNegativeCacheTTL time.Duration `envconfig:"ONDC_AUTH_NEGATIVE_CACHE_TTL" default:"10s"`
+	// ONDCAuthKeyCacheSize caps the resolved and unresolved keys remembered;
+	// the least recently used ones are evicted first
+	ONDCAuthKeyCacheSize int `envconfig:"ONDC_AUTH_KEY_CACHE_SIZE" default:"10000"`
+	ONDCAuthRequireGatewaySignature bool          `envconfig:"ONDC_AUTH_REQUIRE_GATEWAY_SIGNATURE" default:"false"`
+	ONDCAuthClockSkew               time.Duration `envconfig:"ONDC_AUTH_CLOCK_SKEW" default:"5s"`
+}
+
+// RegistryEnvConfig holds the registry endpoint, subscriber credentials,
+// domains and default lookup criteria of a single registry env.
+type RegistryEnvConfig struct {
+	Name         string
+	URL          string
+	SubscriberID string
+	UniqueKeyID  string
+	PrivateKey   string
+	Domains      []string
+	Country      string
+	City         string
+	Type         string
+	// ResponseFormat selects the lookup response parser: auto, legacy or v2
+	ResponseFormat string
 }
 
 func LoadConfig() (*Config, error) {
@@ -36,5 +125,106 @@ func LoadConfig() (*Config, error) {
 		return nil, fmt.Errorf("error processing envconfig: %w", err)
 	}
 
+	if err := config.loadRegistries(); err != nil {
+		return nil, err
+	}
+
+	// Registry statuses are upper case and lifecycles lower case, whatever
+	// case they are configured in
+	lifecycles := make(map[string]string, len(config.RegistryStatusLifecycle))
+	for status, lifecycle := range config.RegistryStatusLifecycle {
+		status, lifecycle = strings.ToUpper(strings.TrimSpace(status)), strings.ToLower(strings.TrimSpace(lifecycle))
+		switch lifecycle {
+		case "active", "pending", "inactive":
+		default:
+			return nil, fmt.Errorf("REGISTRY_STATUS_LIFECYCLE: unknown lifecycle %q for status %s", lifecycle, status)
+		}
+		if existing, ok := lifecycles[status]; ok && existing != lifecycle {
+			return nil, fmt.Errorf("REGISTRY_STATUS_LIFECYCLE: status %s is mapped to both %s and %s", status, existing, lifecycle)
+		}
+		lifecycles[status] = lifecycle
+	}
+	config.RegistryStatusLifecycle = lifecycles
+
+	switch config.RegistryArchiveMode {
+	case "off", "disk", "postgres":
+	default:
+		return nil, fmt.Errorf("REGISTRY_ARCHIVE_MODE: unknown mode %q", config.RegistryArchiveMode)
+	}
+
 	return config, nil
+}
+
+// Registry returns the settings of a configured registry env.
+func (c *Config) Registry(registryEnv string) (RegistryEnvConfig, bool) {
+	registry, ok := c.Registries[registryEnv]
+	return registry, ok
+}
+
+func (c *Config) loadRegistries() error {
+	if len(c.RegistryEnvs) == 0 {
+		c.RegistryEnvs = []string{c.RegistryEnv}
+	}
+
+	c.Registries = make(map[string]RegistryEnvConfig, len(c.RegistryEnvs))
+	for _, name := range c.RegistryEnvs {
+		// Looked up without envconfig so an env never silently inherits the
+		// unprefixed variables of another env
+		prefix := "REGISTRY_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		registry := RegistryEnvConfig{
+			Name:         name,
+			URL:          os.Getenv(prefix + "URL"),
+			SubscriberID: os.Getenv(prefix + "SUBSCRIBER_ID"),
+			UniqueKeyID:  os.Getenv(prefix + "UNIQUE_KEY_ID"),
+			PrivateKey:   os.Getenv(prefix + "PRIVATE_KEY"),
+			Country:      envOrDefault(prefix+"COUNTRY", c.RegistryLookupCountry),
+			City:         envOrDefault(prefix+"CITY", c.RegistryLookupCity),
+			Type:         envOrDefault(prefix+"TYPE", c.RegistryLookupType),
+
+			ResponseFormat: envOrDefault(prefix+"RESPONSE_FORMAT", c.RegistryResponseFormat),
+		}
+		switch registry.ResponseFormat {
+		case "auto", "legacy", "v2":
+		default:
+			return fmt.Errorf("%sRESPONSE_FORMAT: unknown format %q", prefix, registry.ResponseFormat)
+		}
+		if domains := os.Getenv(prefix + "DOMAINS"); domains != "" {
+			registry.Domains = strings.Split(domains, ",")
+		}
+
+		if name == c.RegistryEnv {
+			if registry.URL == "" {
+				registry.URL = c.RegistryURL
+			}
+			if registry.SubscriberID == "" {
+				registry.SubscriberID = c.SubscriberID
+			}
+			if registry.UniqueKeyID == "" {
+				registry.UniqueKeyID = c.UniqueKeyID
+			}
+			if registry.PrivateKey == "" {
+				registry.PrivateKey = c.PrivateKey
+			}
+		}
+		if len(registry.Domains) == 0 {
+			registry.Domains = c.Domains
+		}
+
+		if registry.URL == "" || registry.SubscriberID == "" || registry.UniqueKeyID == "" || registry.PrivateKey == "" {
+			return fmt.Errorf("registry env %s requires %sURL, %sSUBSCRIBER_ID, %sUNIQUE_KEY_ID and %sPRIVATE_KEY", name, prefix, prefix, prefix, prefix)
+		}
+		c.Registries[name] = registry
+	}
+
+	if _, ok := c.Registries[c.RegistryEnv]; !ok {
+		return fmt.Errorf("REGISTRY_ENV %s is not listed in REGISTRY_ENVS", c.RegistryEnv)
+	}
+	return nil
+}
+
+func envOrDefault(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return fallback
 }
\ No newline at end of file
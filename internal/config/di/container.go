@@ -13,7 +13,9 @@ import (
 	registryDomain "adapter/internal/domain/registry_sync"
 	catalogSyncHandler "adapter/internal/handlers/catalog_sync"
 	catalogSyncPorts "adapter/internal/ports/catalog_sync"
+	registryPorts "adapter/internal/ports/registry_sync"
 	permissionsHandler "adapter/internal/handlers/permissions"
+	idempotencyPorts "adapter/internal/ports/idempotency"
 	permissionsPorts "adapter/internal/ports/permissions"
 	"adapter/internal/shared/caching"
 	db "adapter/internal/shared/database"
@@ -26,14 +28,23 @@ type Container struct {
 	DB     *gorm.DB
 	// RedisClient         *redis.Client
 	CacheService        caching.CacheService
+	ONDCService         *registryDomain.ONDCService
 	RegistrySyncHandler *registryHandler.RegistrySyncHandler
 	PermissionsHandler  *permissionsHandler.PermissionsHandler
+	PermissionsGRPC     *permissionsHandler.PermissionsGRPCServer
 	CatalogSyncHandler  *catalogSyncHandler.CatalogSyncHandler
+	IdempotencyRepo     idempotencyPorts.IdempotencyRepository
+	ONDCKeyResolver     *registryDomain.RegistryKeyResolver
 }
 
 func (c *Container) Shutdown(ctx context.Context) error {
 	logger.Info(ctx, "Shutting down container resources...")
 
+	// Stop in-flight registry syncs before the database goes away
+	if c.ONDCService != nil {
+		c.ONDCService.Close()
+	}
+
 	if c.DB != nil {
 		if err := db.Close(); err != nil {
 			logger.Error(ctx, err, "Failed to close database connection")
@@ -71,37 +82,59 @@ func InitContainer() (*Container, error) {
 
 	// Run database migrations using golang-migrate only
 	logger.Info(ctx, "Running database migrations...")
-	if err := database.AutoMigrate(&catalogSyncPorts.Seller{}, &permissionsPorts.Bap{}, &catalogSyncPorts.SellerCatalogState{}, &permissionsPorts.BapAccessPolicy{}); err != nil {
+	if err := database.AutoMigrate(&catalogSyncPorts.Seller{}, &permissionsPorts.Bap{}, &catalogSyncPorts.SellerCatalogState{}, &catalogSyncPorts.SellerChange{}, &catalogSyncPorts.SellerLocation{}, &catalogSyncPorts.PendingPolicyTemplates{}, &permissionsPorts.BapAccessPolicy{}, &permissionsPorts.PolicyTemplate{}, &permissionsPorts.PolicyTemplateEntry{}, &idempotencyPorts.IdempotencyRecord{}, &registryPorts.RegistrySyncRun{}, &registryPorts.RegistrySyncRunDomain{}, &registryPorts.RegistrySyncLock{}, &registryPorts.SubscriberKey{}, &registryPorts.RegistryLookupArchive{}, &registryPorts.HeldDeactivation{}); err != nil {
 		logger.Fatal(ctx, err, "Failed to run database migrations")
 		return nil, fmt.Errorf("failed to run database migrations: %w", err)
 	}
+	if err := EnsureBapAccessPolicyProviderKey(database); err != nil {
+		logger.Fatal(ctx, err, "Failed to migrate bap_access_policy primary key")
+		return nil, fmt.Errorf("failed to migrate bap_access_policy primary key: %w", err)
+	}
+	if err := ClearZeroSellerValidity(database); err != nil {
+		logger.Fatal(ctx, err, "Failed to migrate zero seller validity dates")
+		return nil, fmt.Errorf("failed to migrate zero seller validity dates: %w", err)
+	}
 	logger.Info(ctx, "Database migrations completed successfully")
 
 	// Create instances
 	// cacheService := caching.NewRedisCacheService(redisDB)
 	
 	// Catalog Sync
-	sellerRepo := catalogSyncPorts.NewGormRepository(database)
+	sellerRepo := catalogSyncPorts.NewGormRepository(database).WithBatchSize(cfg.RegistrySyncBatchSize)
 	catalogSyncService := catalogDomain.NewCatalogSyncService(sellerRepo)
 	catalogSyncHandler := catalogSyncHandler.NewCatalogSyncHandler(catalogSyncService)
 
 	// Permissions
-	permissionsRepo := permissionsPorts.NewGormRepository(database)
+	permissionsRepo := permissionsPorts.NewGormRepository(database).WithBatchSize(cfg.RegistrySyncBatchSize)
 	permissionsService := permissions.NewPermissionsService(permissionsRepo)
+	permissionsGRPCServer := permissionsHandler.NewPermissionsGRPCServer(permissionsService)
 	permissionsHandler := permissionsHandler.NewPermissionsHandler(permissionsService)
 
 	// ONDC / Registry Sync
-	ondcService := registryDomain.NewONDCService(sellerRepo, cfg)
+	syncRunRepo := registryPorts.NewGormRepository(database)
+	lookupArchive, err := registryPorts.NewLookupArchive(cfg.RegistryArchiveMode, cfg.RegistryArchiveDir, database)
+	if err != nil {
+		logger.Fatal(ctx, err, "Failed to configure registry response archive")
+		return nil, fmt.Errorf("failed to configure registry response archive: %w", err)
+	}
+	ondcService := registryDomain.NewONDCService(sellerRepo, syncRunRepo, syncRunRepo, syncRunRepo, lookupArchive, syncRunRepo, permissionsService, cfg)
 	registrySyncHandler := registryHandler.NewRegistrySyncHandler(ondcService)
+	ondcKeyResolver := registryDomain.NewRegistryKeyResolver(ondcService, cfg.ONDCAuthKeyCacheTTL, cfg.ONDCAuthNegativeCacheTTL, cfg.ONDCAuthKeyCacheSize)
 
+	// Idempotency
+	idempotencyRepo := idempotencyPorts.NewGormRepository(database)
 
 	return &Container{
 		Config:      cfg,
 		DB:          database,
 		// RedisClient:         redisDB,
 		// CacheService:        cacheService,
+		ONDCService:         ondcService,
 		RegistrySyncHandler: registrySyncHandler,
 		PermissionsHandler:  permissionsHandler,
+		PermissionsGRPC:     permissionsGRPCServer,
 		CatalogSyncHandler:  catalogSyncHandler,
+		IdempotencyRepo:     idempotencyRepo,
+		ONDCKeyResolver:     ondcKeyResolver,
 	}, err
 }
@@ -69,6 +69,42 @@ func MigrateDB(db *gorm.DB) error {
 	return nil
 }
 
+// EnsureBapAccessPolicyProviderKey widens the bap_access_policy primary key to
+// include provider_id on databases created before provider-level policies.
+// AutoMigrate adds the column but never alters an existing primary key.
+func EnsureBapAccessPolicyProviderKey(db *gorm.DB) error {
+	return db.Exec(`
+DO $$
+BEGIN
+	IF NOT EXISTS (
+		SELECT 1
+		FROM information_schema.key_column_usage
+		WHERE table_name = 'bap_access_policy'
+		  AND constraint_name = 'bap_access_policy_pkey'
+		  AND column_name = 'provider_id'
+	) THEN
+		ALTER TABLE bap_access_policy DROP CONSTRAINT IF EXISTS bap_access_policy_pkey;
+		ALTER TABLE bap_access_policy ADD PRIMARY KEY (seller_id, domain, registry_env, bap_id, provider_id);
+	END IF;
+END $$;`).Error
+}
+
+// ClearZeroSellerValidity turns the zero validity dates stored before the
+// validity columns became nullable into NULLs. Left in place, 0001-01-01 reads
+// as a validity that ended long ago, which hides the sellers from every
+// validity-filtered query and gets them deactivated as expired.
+func ClearZeroSellerValidity(db *gorm.DB) error {
+	for _, table := range []string{"sellers", "seller_locations"} {
+		if err := db.Exec(fmt.Sprintf(`UPDATE %s SET valid_from = NULL WHERE valid_from <= '0001-01-02'`, table)).Error; err != nil {
+			return err
+		}
+		if err := db.Exec(fmt.Sprintf(`UPDATE %s SET valid_until = NULL WHERE valid_until <= '0001-01-02'`, table)).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func GetMigrationMode() string {
 	return "migrate"
 }
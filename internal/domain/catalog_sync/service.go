@@ -1,9 +1,12 @@
 package catalogPorts
 
 import (
+	"encoding/json"
+
 	catalogPorts "adapter/internal/ports/catalog_sync"
 	"gorm.io/gorm"
 	"strings"
+	"time"
 )
 
 type CatalogSyncService struct {
@@ -15,8 +18,8 @@ func NewCatalogSyncService(repo catalogPorts.SellerRepository) *CatalogSyncServi
 }
 
 
-func (s *CatalogSyncService) GetPendingCatalogSyncSellers(domain, registryEnv, status string, limit, page, offset int) (*catalogPorts.PendingCatalogSyncSellersResponse, error) {
-	sellers, err := s.repo.GetPendingSellers(domain, registryEnv, status, limit, offset)
+func (s *CatalogSyncService) GetPendingCatalogSyncSellers(domain, registryEnv, status, city string, limit, page, offset int) (*catalogPorts.PendingCatalogSyncSellersResponse, error) {
+	sellers, err := s.repo.GetPendingSellers(domain, registryEnv, status, city, limit, offset)
 	if err != nil {
 		return nil, err
 	}
@@ -37,6 +40,7 @@ func (s *CatalogSyncService) GetPendingCatalogSyncSellers(domain, registryEnv, s
 		Domain:       domain,
 		RegistryEnv:  registryEnv,
 		StatusFilter: statusFilter,
+		City:         city,
 		Sellers:      sellers,
 		Page: catalogPorts.PageInfo{
 			Limit:    limit,
@@ -64,6 +68,8 @@ func (s *CatalogSyncService) GetSyncStatus(sellerID, domain, registryEnv string)
 			RegistryEnv:        seller.RegistryEnv,
 			Status:             string(catalogPorts.CatalogStatusNotSynced), // Default status
 			RegistryLastSeenAt: seller.LastSeenInReg,
+			SellerActive:       seller.Active && seller.WithinValidity(time.Now()),
+			RegistryValidUntil: seller.ValidUntil,
 		}, nil
 	}
 
@@ -77,5 +83,111 @@ func (s *CatalogSyncService) GetSyncStatus(sellerID, domain, registryEnv string)
 		LastError:          state.LastError,
 		SyncVersion:        state.SyncVersion,
 		RegistryLastSeenAt: seller.LastSeenInReg,
+		SellerActive:       seller.Active && seller.WithinValidity(time.Now()),
+		RegistryValidUntil: seller.ValidUntil,
+	}, nil
+}
+
+func (s *CatalogSyncService) GetSellerChanges(sellerID, domain, registryEnv string, limit, page, offset int) (*catalogPorts.SellerChangesResponse, error) {
+	changes, err := s.repo.GetSellerChanges(sellerID, domain, registryEnv, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	hasMore := len(changes) > limit
+	if hasMore {
+		changes = changes[:limit] // Trim the extra record fetched for hasMore check
+	}
+
+	response := &catalogPorts.SellerChangesResponse{
+		SellerID:    sellerID,
+		RegistryEnv: registryEnv,
+		Changes:     []catalogPorts.SellerChangeResponse{},
+		Page: catalogPorts.PageInfo{
+			Limit:   limit,
+			Page:    page,
+			HasMore: hasMore,
+		},
+	}
+	for _, change := range changes {
+		fieldChanges := []catalogPorts.FieldChange{}
+		_ = json.Unmarshal([]byte(change.Changes), &fieldChanges)
+		response.Changes = append(response.Changes, catalogPorts.SellerChangeResponse{
+			Domain:      change.Domain,
+			RegistryEnv: change.RegistryEnv,
+			RunID:       change.RunID,
+			ChangeType:  string(change.ChangeType),
+			Changes:     fieldChanges,
+			ChangedAt:   change.ChangedAt,
+		})
+	}
+	return response, nil
+}
+
+func (s *CatalogSyncService) GetExpiringSellers(domain, registryEnv string, within time.Duration, limit, page, offset int) (*catalogPorts.ExpiringSellersResponse, error) {
+	expiringBy := time.Now().Add(within)
+	sellers, err := s.repo.GetExpiringSellers(domain, registryEnv, expiringBy, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	hasMore := len(sellers) > limit
+	if hasMore {
+		sellers = sellers[:limit] // Trim the extra record fetched for hasMore check
+	}
+
+	response := &catalogPorts.ExpiringSellersResponse{
+		Domain:      domain,
+		RegistryEnv: registryEnv,
+		ExpiringBy:  expiringBy,
+		Sellers:     []catalogPorts.ExpiringSellerInfo{},
+		Page: catalogPorts.PageInfo{
+			Limit:   limit,
+			Page:    page,
+			HasMore: hasMore,
+		},
+	}
+	for _, seller := range sellers {
+		if seller.ValidUntil == nil {
+			continue
+		}
+		response.Sellers = append(response.Sellers, catalogPorts.ExpiringSellerInfo{
+			SellerID:   seller.SellerID,
+			Domain:     seller.Domain,
+			Status:     seller.Status,
+			ValidUntil: *seller.ValidUntil,
+			LastSeenAt: seller.LastSeenInReg,
+		})
+	}
+	return response, nil
+}
+
+func (s *CatalogSyncService) GetSellerLocations(sellerID, domain, registryEnv string) (*catalogPorts.SellerLocationsResponse, error) {
+	locations, err := s.repo.GetSellerLocations(sellerID, domain, registryEnv)
+	if err != nil {
+		return nil, err
+	}
+	if len(locations) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &catalogPorts.SellerLocationsResponse{
+		SellerID:    sellerID,
+		RegistryEnv: registryEnv,
+		Locations:   locations,
+	}, nil
+}
+
+func (s *CatalogSyncService) GetCityCoverage(domain, registryEnv string) (*catalogPorts.CityCoverageResponse, error) {
+	cities, err := s.repo.GetCityCoverage(domain, registryEnv)
+	if err != nil {
+		return nil, err
+	}
+	if cities == nil {
+		cities = []catalogPorts.CityCoverage{}
+	}
+	return &catalogPorts.CityCoverageResponse{
+		Domain:      domain,
+		RegistryEnv: registryEnv,
+		Cities:      cities,
 	}, nil
 }
@@ -27,6 +27,7 @@ func (s *PermissionsService) UpdatePermissions(updates []ports.PermissionsUpdate
 			Domain:         update.Domain,
 			RegistryEnv:    update.RegistryEnv,
 			BapID:          update.BapID,
+			ProviderID:     update.ProviderID,
 			Decision:       ports.AccessDecision(update.Decision),
 			DecisionSource: ports.DecisionSource(update.DecisionSource),
 			DecidedAt:      time.Now(),
@@ -45,6 +46,7 @@ func (s *PermissionsService) UpdatePermissions(updates []ports.PermissionsUpdate
 			Domain:      update.Domain,
 			RegistryEnv: update.RegistryEnv,
 			BapID:       update.BapID,
+			ProviderID:  update.ProviderID,
 			Decision:    update.Decision,
 			Stored:      false, // Will be set to true after successful DB operation
 		})
@@ -92,37 +94,58 @@ func (s *PermissionsService) QueryPermissions(req ports.PermissionsQueryRequest)
 		}
 	}
 
-	policies, err := s.repo.QueryBapAccessPolicies(req.BapID, req.Domain, req.RegistryEnv, req.SellerIDs)
+	policies, err := s.repo.QueryBapAccessPolicies(req.BapID, req.Domain, req.RegistryEnv, req.SellerIDs, req.ProviderIDs)
 	if err != nil {
 		return nil, err
 	}
 
 	policyMap := make(map[string]ports.BapAccessPolicy)
 	for _, p := range policies {
-		policyMap[p.SellerID] = p
+		policyMap[p.SellerID+"|"+p.ProviderID] = p
+	}
+
+	// Without provider_ids only seller-level decisions are returned; with them,
+	// each provider gets its own decision, falling back to the seller-level one.
+	providerIDs := req.ProviderIDs
+	if len(providerIDs) == 0 {
+		providerIDs = []string{""}
 	}
 
 	var permissions []ports.PermissionDetail
 	for _, sellerID := range req.SellerIDs {
-		if policy, ok := policyMap[sellerID]; ok {
-			permissions = append(permissions, ports.PermissionDetail{
-				SellerID:       policy.SellerID,
-				Domain:         policy.Domain,
-				RegistryEnv:    policy.RegistryEnv,
-				BapID:          policy.BapID,
-				Decision:       string(policy.Decision),
-				DecisionSource: (*string)(&policy.DecisionSource),
-				DecidedAt:      &policy.DecidedAt,
-				ExpiresAt:      policy.ExpiresAt,
-			})
-		} else if req.IncludeNoPolicy {
-			permissions = append(permissions, ports.PermissionDetail{
-				SellerID:    sellerID,
-				Domain:      req.Domain,
-				RegistryEnv: req.RegistryEnv,
-				BapID:       req.BapID,
-				Decision:    "NO_POLICY",
-			})
+		for _, providerID := range providerIDs {
+			policy, ok := policyMap[sellerID+"|"+providerID]
+			if !ok && providerID != "" {
+				policy, ok = policyMap[sellerID+"|"]
+			}
+
+			if ok {
+				level := ports.PolicyLevelSeller
+				if policy.ProviderID != "" {
+					level = ports.PolicyLevelProvider
+				}
+				permissions = append(permissions, ports.PermissionDetail{
+					SellerID:       policy.SellerID,
+					Domain:         policy.Domain,
+					RegistryEnv:    policy.RegistryEnv,
+					BapID:          policy.BapID,
+					ProviderID:     providerID,
+					Decision:       string(policy.Decision),
+					PolicyLevel:    string(level),
+					DecisionSource: (*string)(&policy.DecisionSource),
+					DecidedAt:      &policy.DecidedAt,
+					ExpiresAt:      policy.ExpiresAt,
+				})
+			} else if req.IncludeNoPolicy {
+				permissions = append(permissions, ports.PermissionDetail{
+					SellerID:    sellerID,
+					Domain:      req.Domain,
+					RegistryEnv: req.RegistryEnv,
+					BapID:       req.BapID,
+					ProviderID:  providerID,
+					Decision:    "NO_POLICY",
+				})
+			}
 		}
 	}
 
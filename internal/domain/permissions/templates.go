@@ -0,0 +1,173 @@
+package permissions
+
+import (
+	"fmt"
+	"time"
+
+	ports "adapter/internal/ports/permissions"
+)
+
+func (s *PermissionsService) SavePolicyTemplate(name string, req ports.PolicyTemplateRequest) (*ports.PolicyTemplateResponse, error) {
+	template := &ports.PolicyTemplate{
+		Name:        name,
+		Description: req.Description,
+		RegistryEnv: req.RegistryEnv,
+		AutoApply:   req.AutoApply,
+	}
+	// A repeated domain and BAP keeps the last entry given
+	entryIndex := make(map[string]int, len(req.Entries))
+	for _, entry := range req.Entries {
+		templateEntry := ports.PolicyTemplateEntry{
+			Domain:   entry.Domain,
+			BapID:    entry.BapID,
+			Decision: ports.AccessDecision(entry.Decision),
+			Reason:   entry.Reason,
+		}
+		key := entry.Domain + "|" + entry.BapID
+		if i, ok := entryIndex[key]; ok {
+			template.Entries[i] = templateEntry
+			continue
+		}
+		entryIndex[key] = len(template.Entries)
+		template.Entries = append(template.Entries, templateEntry)
+	}
+
+	if err := s.repo.SavePolicyTemplate(template); err != nil {
+		return nil, err
+	}
+	return s.GetPolicyTemplate(name)
+}
+
+func (s *PermissionsService) GetPolicyTemplate(name string) (*ports.PolicyTemplateResponse, error) {
+	template, err := s.repo.GetPolicyTemplate(name)
+	if err != nil {
+		return nil, err // Could be gorm.ErrRecordNotFound
+	}
+	return toPolicyTemplateResponse(*template), nil
+}
+
+func (s *PermissionsService) ListPolicyTemplates() ([]ports.PolicyTemplateResponse, error) {
+	templates, err := s.repo.GetPolicyTemplates()
+	if err != nil {
+		return nil, err
+	}
+
+	responses := []ports.PolicyTemplateResponse{}
+	for _, template := range templates {
+		responses = append(responses, *toPolicyTemplateResponse(template))
+	}
+	return responses, nil
+}
+
+func (s *PermissionsService) DeletePolicyTemplate(name string) error {
+	return s.repo.DeletePolicyTemplate(name)
+}
+
+// ApplyPolicyTemplatesToNewSellers applies every auto-apply template matching the
+// registry env to sellers that were just discovered. Decisions that already exist
+// are left untouched. When several entries target the same BAP, a domain-specific
+// entry wins over a catch-all one, then templates are taken in name order.
+func (s *PermissionsService) ApplyPolicyTemplatesToNewSellers(registryEnv, domain string, sellerIDs []string) (int, error) {
+	if len(sellerIDs) == 0 {
+		return 0, nil
+	}
+
+	templates, err := s.repo.GetAutoApplyPolicyTemplates(registryEnv)
+	if err != nil {
+		return 0, err
+	}
+
+	type selectedEntry struct {
+		template string
+		entry    ports.PolicyTemplateEntry
+	}
+	selected := make(map[string]selectedEntry)
+	var bapOrder []string
+	for _, template := range templates {
+		for _, entry := range template.Entries {
+			if entry.Domain != "" && entry.Domain != domain {
+				continue
+			}
+			current, exists := selected[entry.BapID]
+			if !exists {
+				bapOrder = append(bapOrder, entry.BapID)
+			} else if current.entry.Domain != "" || entry.Domain == "" {
+				continue
+			}
+			selected[entry.BapID] = selectedEntry{template: template.Name, entry: entry}
+		}
+	}
+	if len(selected) == 0 {
+		return 0, nil
+	}
+
+	// A seller listed twice would otherwise get two rows in one statement
+	seenSellers := make(map[string]bool, len(sellerIDs))
+	uniqueSellerIDs := make([]string, 0, len(sellerIDs))
+	for _, sellerID := range sellerIDs {
+		if !seenSellers[sellerID] {
+			seenSellers[sellerID] = true
+			uniqueSellerIDs = append(uniqueSellerIDs, sellerID)
+		}
+	}
+
+	now := time.Now()
+	bapsToUpsert := make(map[string]ports.Bap)
+	var policies []ports.BapAccessPolicy
+	for _, bapID := range bapOrder {
+		chosen := selected[bapID]
+		bapsToUpsert[bapID] = ports.Bap{BapID: bapID}
+
+		reason := chosen.entry.Reason
+		if reason == nil {
+			defaultReason := fmt.Sprintf("Applied from policy template %s", chosen.template)
+			reason = &defaultReason
+		}
+
+		for _, sellerID := range uniqueSellerIDs {
+			policies = append(policies, ports.BapAccessPolicy{
+				SellerID:       sellerID,
+				Domain:         domain,
+				RegistryEnv:    registryEnv,
+				BapID:          bapID,
+				Decision:       chosen.entry.Decision,
+				DecisionSource: ports.SourcePolicyTemplate,
+				DecidedAt:      now,
+				Reason:         reason,
+			})
+		}
+	}
+
+	// Upsert BAPs first to satisfy foreign key constraints
+	if err := s.repo.UpsertBaps(bapsToUpsert); err != nil {
+		return 0, err
+	}
+
+	inserted, err := s.repo.InsertBapAccessPoliciesIfAbsent(policies)
+	if err != nil {
+		return 0, err
+	}
+	return int(inserted), nil
+}
+
+func toPolicyTemplateResponse(template ports.PolicyTemplate) *ports.PolicyTemplateResponse {
+	entries := []ports.PolicyTemplateEntryDTO{}
+	for _, entry := range template.Entries {
+		entries = append(entries, ports.PolicyTemplateEntryDTO{
+			Domain:   entry.Domain,
+			BapID:    entry.BapID,
+			Decision: string(entry.Decision),
+			Reason:   entry.Reason,
+		})
+	}
+
+	return &ports.PolicyTemplateResponse{
+		Name:        template.Name,
+		Description: template.Description,
+		RegistryEnv: template.RegistryEnv,
+		AutoApply:   template.AutoApply,
+		Entries:     entries,
+		CreatedAt:   template.CreatedAt,
+		UpdatedAt:   template.UpdatedAt,
+	}
+}
@@ -0,0 +1,115 @@
+package domain
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	registryPorts "adapter/internal/ports/registry_sync"
+	"adapter/internal/shared/log"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrLookupArchiveDisabled is returned for a replay when no response
+	// archive is configured.
+	ErrLookupArchiveDisabled = errors.New("registry response archive is disabled")
+	// ErrReplayRunNotFound is returned for a replay of an unknown run.
+	ErrReplayRunNotFound = errors.New("replayed registry sync run not found")
+	// ErrReplayEnvMismatch is returned when a replay names a registry env
+	// other than the one the replayed run synced.
+	ErrReplayEnvMismatch = errors.New("registry env differs from the replayed run")
+)
+
+// fetchDomainSellers returns the registry's subscribers for a domain. A replay
+// run reads the response archived by the run it replays; any other run calls
+// the registry and, when archiving is enabled, archives the raw response.
+func (s *ONDCService) fetchDomainSellers(ctx context.Context, run *registryPorts.RegistrySyncRun, registryEnv, domain string, criteria registryPorts.LookupCriteria) (ONDCLookupResponse, error) {
+	if run.ReplayOfRunID != nil {
+		body, err := s.loadLookupResponse(ctx, registryEnv, *run.ReplayOfRunID, domain)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load archived registry response of run %s: %w", *run.ReplayOfRunID, err)
+		}
+		return decodeLookupResponse(body, s.registries[registryEnv].ResponseFormat)
+	}
+
+	body, err := s.fetchRegistryResponse(ctx, registryEnv, domain, criteria)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch sellers from registry: %w", err)
+	}
+	if s.archive != nil {
+		// A failed archive must not fail the sync it was meant to document
+		if err := s.archiveLookupResponse(ctx, run.RunID, registryEnv, domain, body); err != nil {
+			log.Error(ctx, err, fmt.Sprintf("Failed to archive registry response for domain %s", domain))
+		}
+	}
+	return decodeLookupResponse(body, s.registries[registryEnv].ResponseFormat)
+}
+
+func (s *ONDCService) archiveLookupResponse(ctx context.Context, runID, registryEnv, domain string, body []byte) error {
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	if _, err := zw.Write(body); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return s.archive.SaveLookupResponse(ctx, &registryPorts.RegistryLookupArchive{
+		RunID:       runID,
+		Domain:      domain,
+		RegistryEnv: registryEnv,
+		Body:        compressed.Bytes(),
+		Size:        len(body),
+		ArchivedAt:  time.Now(),
+	})
+}
+
+func (s *ONDCService) loadLookupResponse(ctx context.Context, registryEnv, runID, domain string) ([]byte, error) {
+	if s.archive == nil {
+		return nil, ErrLookupArchiveDisabled
+	}
+	archive, err := s.archive.GetLookupResponse(ctx, registryEnv, runID, domain)
+	if err != nil {
+		return nil, err
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(archive.Body))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
+
+// resolveReplay prepares a replay request from the run it replays: the replay
+// syncs the same registry env with the same lookup criteria, and by default
+// the same domains.
+func (s *ONDCService) resolveReplay(req registryPorts.SyncRegistryRequest) (registryPorts.SyncRegistryRequest, error) {
+	if s.archive == nil {
+		return req, ErrLookupArchiveDisabled
+	}
+	source, err := s.GetSyncRun(req.ReplayRunID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return req, fmt.Errorf("%w: %s", ErrReplayRunNotFound, req.ReplayRunID)
+		}
+		return req, err
+	}
+	if req.RegistryEnv != "" && req.RegistryEnv != source.RegistryEnv {
+		return req, fmt.Errorf("%w: %s", ErrReplayEnvMismatch, source.RegistryEnv)
+	}
+	if source.ReplayOfRunID != nil {
+		// Replays archive nothing themselves, so replay the original run
+		req.ReplayRunID = *source.ReplayOfRunID
+	}
+	req.RegistryEnv = source.RegistryEnv
+	req.LookupCriteria = source.LookupCriteria
+	if len(req.Domains) == 0 {
+		req.Domains = source.RequestedDomains
+	}
+	return req, nil
+}
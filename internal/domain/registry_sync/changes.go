@@ -0,0 +1,65 @@
+package domain
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	catalogPorts "adapter/internal/ports/catalog_sync"
+)
+
+// sellerFields lists the registry fields tracked in the seller changelog.
+var sellerFields = []struct {
+	name  string
+	value func(seller catalogPorts.Seller, sub Subscriber) string
+}{
+	{"status", func(seller catalogPorts.Seller, _ Subscriber) string { return seller.Status }},
+	{"subscriber_url", func(seller catalogPorts.Seller, _ Subscriber) string { return seller.SubscriberURL }},
+	{"msn", func(seller catalogPorts.Seller, _ Subscriber) string { return strconv.FormatBool(seller.MSN) }},
+	{"country", func(seller catalogPorts.Seller, _ Subscriber) string { return seller.Country }},
+	{"city", func(seller catalogPorts.Seller, _ Subscriber) string { return seller.City }},
+	{"valid_from", func(seller catalogPorts.Seller, _ Subscriber) string { return formatChangeTime(seller.ValidFrom) }},
+	{"valid_until", func(seller catalogPorts.Seller, _ Subscriber) string { return formatChangeTime(seller.ValidUntil) }},
+	{"active", func(seller catalogPorts.Seller, _ Subscriber) string { return strconv.FormatBool(seller.Active) }},
+	{"ukId", func(_ catalogPorts.Seller, sub Subscriber) string { return sub.UkID }},
+	{"signing_public_key", func(_ catalogPorts.Seller, sub Subscriber) string { return sub.SigningKey }},
+	{"encr_public_key", func(_ catalogPorts.Seller, sub Subscriber) string { return sub.EncryptionKey }},
+}
+
+// diffSellers returns the tracked fields that differ between two versions of
+// a seller. A zero previous version yields every non-empty field of current.
+func diffSellers(previous, current catalogPorts.Seller) []catalogPorts.FieldChange {
+	var previousSub, currentSub Subscriber
+	_ = json.Unmarshal([]byte(previous.RegistryRaw), &previousSub)
+	_ = json.Unmarshal([]byte(current.RegistryRaw), &currentSub)
+
+	changes := []catalogPorts.FieldChange{}
+	for _, field := range sellerFields {
+		oldValue := field.value(previous, previousSub)
+		newValue := field.value(current, currentSub)
+		if oldValue != newValue {
+			changes = append(changes, catalogPorts.FieldChange{Field: field.name, Old: oldValue, New: newValue})
+		}
+	}
+	return changes
+}
+
+func newSellerChange(runID string, seller catalogPorts.Seller, changeType catalogPorts.SellerChangeType, fieldChanges []catalogPorts.FieldChange, changedAt time.Time) catalogPorts.SellerChange {
+	raw, _ := json.Marshal(fieldChanges)
+	return catalogPorts.SellerChange{
+		SellerID:    seller.SellerID,
+		Domain:      seller.Domain,
+		RegistryEnv: seller.RegistryEnv,
+		RunID:       runID,
+		ChangeType:  changeType,
+		Changes:     string(raw),
+		ChangedAt:   changedAt,
+	}
+}
+
+func formatChangeTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,207 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"adapter/internal/config"
+)
+
+// ErrRegistryUnavailable is returned without contacting the registry while
+// its circuit breaker is open.
+var ErrRegistryUnavailable = errors.New("registry unavailable: circuit breaker open")
+
+// RegistryStatusError is returned when the registry answers a lookup with a
+// non-200 status.
+type RegistryStatusError struct {
+	StatusCode int
+	Body       string
+	RetryAfter time.Duration
+}
+
+func (e *RegistryStatusError) Error() string {
+	return fmt.Sprintf("API request failed with status %d: %s", e.StatusCode, e.Body)
+}
+
+// retryPolicy bounds how often and how quickly a failed lookup is retried.
+type retryPolicy struct {
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+}
+
+func newRetryPolicy(cfg *config.Config) retryPolicy {
+	policy := retryPolicy{
+		maxAttempts: cfg.RegistryRetryMaxAttempts,
+		baseDelay:   cfg.RegistryRetryBaseDelay,
+		maxDelay:    cfg.RegistryRetryMaxDelay,
+	}
+	if policy.maxAttempts < 1 {
+		policy.maxAttempts = 1
+	}
+	if policy.baseDelay <= 0 {
+		policy.baseDelay = 500 * time.Millisecond
+	}
+	if policy.maxDelay < policy.baseDelay {
+		policy.maxDelay = policy.baseDelay
+	}
+	return policy
+}
+
+// backoff returns the wait before the given retry (1 for the first retry),
+// drawn uniformly between zero and the exponential ceiling so that replicas
+// retrying the same outage spread out. A Retry-After hint from the registry
+// raises the wait, still capped at the maximum delay.
+func (p retryPolicy) backoff(retry int, retryAfter time.Duration) time.Duration {
+	ceiling := p.maxDelay
+	if shift := retry - 1; shift < 32 {
+		if d := p.baseDelay << shift; d > 0 && d < ceiling {
+			ceiling = d
+		}
+	}
+	wait := rand.N(ceiling) + 1
+	if retryAfter > wait {
+		wait = min(retryAfter, p.maxDelay)
+	}
+	return wait
+}
+
+// isRetryable reports whether a lookup failure is transient. Rejections such
+// as signature or request errors are returned to the caller at once.
+func isRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var statusErr *RegistryStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusRequestTimeout ||
+			statusErr.StatusCode == http.StatusTooManyRequests ||
+			statusErr.StatusCode >= 500
+	}
+	// Anything else failed before a response was received
+	return true
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds.
+func parseRetryAfter(value string) time.Duration {
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+func (s breakerState) String() string {
+	switch s {
+	case breakerOpen:
+		return "open"
+	case breakerHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// circuitBreaker fails lookups fast once a registry has failed repeatedly.
+// After the open timeout a single probe is let through: its success closes
+// the breaker, its failure opens it again.
+type circuitBreaker struct {
+	failureThreshold int
+	openTimeout      time.Duration
+
+	mu       sync.Mutex
+	state    breakerState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+func newCircuitBreaker(cfg *config.Config) *circuitBreaker {
+	threshold := cfg.RegistryBreakerFailureThreshold
+	if threshold < 1 {
+		threshold = 5
+	}
+	openTimeout := cfg.RegistryBreakerOpenTimeout
+	if openTimeout <= 0 {
+		openTimeout = 30 * time.Second
+	}
+	return &circuitBreaker{failureThreshold: threshold, openTimeout: openTimeout}
+}
+
+// allow returns ErrRegistryUnavailable while the breaker is open or while its
+// half-open probe is still in flight.
+func (b *circuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == breakerOpen && time.Since(b.openedAt) >= b.openTimeout {
+		b.state = breakerHalfOpen
+	}
+	switch b.state {
+	case breakerOpen:
+		return ErrRegistryUnavailable
+	case breakerHalfOpen:
+		if b.probing {
+			return ErrRegistryUnavailable
+		}
+		b.probing = true
+	}
+	return nil
+}
+
+// record updates the breaker with the outcome of an attempt that allow let
+// through and returns the state it moved to, if it changed.
+func (b *circuitBreaker) record(failed bool) (breakerState, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	previous := b.state
+	b.probing = false
+	if !failed {
+		b.state = breakerClosed
+		b.failures = 0
+		return b.state, b.state != previous
+	}
+
+	b.failures++
+	if b.state == breakerHalfOpen || b.failures >= b.failureThreshold {
+		b.state = breakerOpen
+		b.openedAt = time.Now()
+	}
+	return b.state, b.state != previous
+}
+
+// abandon releases a half-open probe without changing the breaker state, for
+// attempts whose outcome says nothing about registry health: the caller gave
+// up on it or the registry rejected the request itself.
+func (b *circuitBreaker) abandon() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// sleepCtx waits for d or until ctx is done.
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
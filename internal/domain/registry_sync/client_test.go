@@ -0,0 +1,250 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"adapter/internal/config"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	type step struct {
+		// allow, succeed, fail, abandon or elapse (the open timeout)
+		op string
+		// for allow, whether the attempt is let through
+		allowed bool
+		want    breakerState
+	}
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "failures below the threshold keep it closed",
+			steps: []step{
+				{op: "allow", allowed: true, want: breakerClosed},
+				{op: "fail", want: breakerClosed},
+				{op: "allow", allowed: true, want: breakerClosed},
+				{op: "fail", want: breakerClosed},
+				{op: "allow", allowed: true, want: breakerClosed},
+			},
+		},
+		{
+			name: "a success resets the failure count",
+			steps: []step{
+				{op: "fail", want: breakerClosed},
+				{op: "fail", want: breakerClosed},
+				{op: "succeed", want: breakerClosed},
+				{op: "fail", want: breakerClosed},
+				{op: "fail", want: breakerClosed},
+				{op: "allow", allowed: true, want: breakerClosed},
+			},
+		},
+		{
+			name: "the threshold opens it",
+			steps: []step{
+				{op: "fail", want: breakerClosed},
+				{op: "fail", want: breakerClosed},
+				{op: "fail", want: breakerOpen},
+				{op: "allow", allowed: false, want: breakerOpen},
+			},
+		},
+		{
+			name: "a single probe is let through after the open timeout",
+			steps: []step{
+				{op: "fail"}, {op: "fail"}, {op: "fail", want: breakerOpen},
+				{op: "elapse", want: breakerOpen},
+				{op: "allow", allowed: true, want: breakerHalfOpen},
+				{op: "allow", allowed: false, want: breakerHalfOpen},
+			},
+		},
+		{
+			name: "a successful probe closes it",
+			steps: []step{
+				{op: "fail"}, {op: "fail"}, {op: "fail", want: breakerOpen},
+				{op: "elapse", want: breakerOpen},
+				{op: "allow", allowed: true, want: breakerHalfOpen},
+				{op: "succeed", want: breakerClosed},
+				{op: "allow", allowed: true, want: breakerClosed},
+			},
+		},
+		{
+			name: "a failed probe opens it again",
+			steps: []step{
+				{op: "fail"}, {op: "fail"}, {op: "fail", want: breakerOpen},
+				{op: "elapse", want: breakerOpen},
+				{op: "allow", allowed: true, want: breakerHalfOpen},
+				{op: "fail", want: breakerOpen},
+				{op: "allow", allowed: false, want: breakerOpen},
+			},
+		},
+		{
+			name: "an abandoned probe leaves it half-open for the next one",
+			steps: []step{
+				{op: "fail"}, {op: "fail"}, {op: "fail", want: breakerOpen},
+				{op: "elapse", want: breakerOpen},
+				{op: "allow", allowed: true, want: breakerHalfOpen},
+				{op: "abandon", want: breakerHalfOpen},
+				{op: "allow", allowed: true, want: breakerHalfOpen},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newCircuitBreaker(&config.Config{RegistryBreakerFailureThreshold: 3, RegistryBreakerOpenTimeout: time.Minute})
+			for i, s := range tt.steps {
+				switch s.op {
+				case "allow":
+					err := b.allow()
+					if allowed := err == nil; allowed != s.allowed {
+						t.Fatalf("step %d: allow returned %v, want allowed=%v", i, err, s.allowed)
+					}
+					if err != nil && !errors.Is(err, ErrRegistryUnavailable) {
+						t.Fatalf("step %d: allow returned %v, want %v", i, err, ErrRegistryUnavailable)
+					}
+				case "succeed":
+					b.record(false)
+				case "fail":
+					b.record(true)
+				case "abandon":
+					b.abandon()
+				case "elapse":
+					b.openedAt = b.openedAt.Add(-b.openTimeout)
+				}
+				if b.state != s.want {
+					t.Fatalf("step %d (%s): breaker is %s, want %s", i, s.op, b.state, s.want)
+				}
+			}
+		})
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"connection failure", errors.New("connection refused"), true},
+		{"server error", &RegistryStatusError{StatusCode: http.StatusBadGateway}, true},
+		{"request timeout", &RegistryStatusError{StatusCode: http.StatusRequestTimeout}, true},
+		{"rate limited", &RegistryStatusError{StatusCode: http.StatusTooManyRequests}, true},
+		{"wrapped server error", fmt.Errorf("lookup: %w", &RegistryStatusError{StatusCode: http.StatusServiceUnavailable}), true},
+		{"unauthorized", &RegistryStatusError{StatusCode: http.StatusUnauthorized}, false},
+		{"not found", &RegistryStatusError{StatusCode: http.StatusNotFound}, false},
+		{"canceled", context.Canceled, false},
+		{"deadline exceeded", fmt.Errorf("lookup: %w", context.DeadlineExceeded), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRetryable(tt.err); got != tt.want {
+				t.Errorf("isRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	policy := newRetryPolicy(&config.Config{
+		RegistryRetryMaxAttempts: 5,
+		RegistryRetryBaseDelay:   100 * time.Millisecond,
+		RegistryRetryMaxDelay:    time.Second,
+	})
+	tests := []struct {
+		name       string
+		retry      int
+		retryAfter time.Duration
+		// the wait is drawn from (min, max]
+		min, max time.Duration
+	}{
+		{name: "first retry", retry: 1, max: 100 * time.Millisecond},
+		{name: "doubles per retry", retry: 3, max: 400 * time.Millisecond},
+		{name: "capped at the maximum delay", retry: 10, max: time.Second},
+		{name: "far retries do not overflow", retry: 100, max: time.Second},
+		{name: "retry-after raises the wait", retry: 1, retryAfter: 700 * time.Millisecond, min: 700*time.Millisecond - 1, max: 700 * time.Millisecond},
+		{name: "retry-after is capped too", retry: 1, retryAfter: time.Minute, min: time.Second - 1, max: time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for range 100 {
+				if wait := policy.backoff(tt.retry, tt.retryAfter); wait <= tt.min || wait > tt.max {
+					t.Fatalf("backoff(%d, %s) = %s, want within (%s, %s]", tt.retry, tt.retryAfter, wait, tt.min, tt.max)
+				}
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"3", 3 * time.Second},
+		{"0", 0},
+		{"-1", 0},
+		{"Wed, 21 Oct 2026 07:28:00 GMT", 0},
+	}
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.value); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
+
+// TestLookupRawBreaker checks which registry answers count towards the
+// circuit breaker of a half-open registry.
+func TestLookupRawBreaker(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		want   breakerState
+	}{
+		{name: "success closes it", status: http.StatusOK, want: breakerClosed},
+		{name: "transient failure opens it", status: http.StatusServiceUnavailable, want: breakerOpen},
+		{name: "rejection leaves it half-open", status: http.StatusUnauthorized, want: breakerHalfOpen},
+		{name: "missing subscriber leaves it half-open", status: http.StatusNotFound, want: breakerHalfOpen},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests.Add(1)
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte("[]"))
+			}))
+			t.Cleanup(ts.Close)
+
+			h := newSyncHarness(t, "/lookup", nil, fixturePath("registry.json"))
+			registry, err := h.service.Registry(testRegistryEnv)
+			if err != nil {
+				t.Fatal(err)
+			}
+			registry.URL = ts.URL
+
+			breaker := h.service.breakers[registry.Name]
+			breaker.state = breakerOpen
+			breaker.openedAt = time.Now().Add(-breaker.openTimeout)
+
+			_, _ = h.service.lookupRaw(context.Background(), registry, ONDCLookupRequest{Domain: "ONDC:RET10"})
+			if breaker.state != tt.want {
+				t.Errorf("breaker is %s after a %d, want %s", breaker.state, tt.status, tt.want)
+			}
+			if breaker.probing {
+				t.Error("the probe is still marked in flight")
+			}
+			// A single probe is let through, never retried
+			if n := requests.Load(); n != 1 {
+				t.Errorf("registry got %d requests, want 1", n)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	catalogPorts "adapter/internal/ports/catalog_sync"
+	registryPorts "adapter/internal/ports/registry_sync"
+	"adapter/internal/shared/log"
+)
+
+// ErrDeactivationHoldResolved is returned when approving or discarding a hold
+// that is no longer pending.
+var ErrDeactivationHoldResolved = errors.New("held deactivations already resolved")
+
+// deactivationThresholdExceeded explains why removing the given number of the
+// domain's active sellers needs approval, or returns "" when it does not. cause
+// says why the sellers are removed, e.g. "missing from the registry".
+func (s *ONDCService) deactivationThresholdExceeded(removed, active int, cause string) string {
+	if removed == 0 {
+		return ""
+	}
+	if s.maxDeactivations > 0 && removed > s.maxDeactivations {
+		return fmt.Sprintf("%d sellers %s exceeds the limit of %d", removed, cause, s.maxDeactivations)
+	}
+	if s.maxDeactivationPercent > 0 && active > 0 {
+		if percent := float64(removed) * 100 / float64(active); percent > s.maxDeactivationPercent {
+			return fmt.Sprintf("%d of %d active sellers (%.1f%%) %s exceeds the limit of %.1f%%", removed, active, percent, cause, s.maxDeactivationPercent)
+		}
+	}
+	return ""
+}
+
+// holdDeactivations records deactivations withheld for approval and returns
+// the hold ID. runID is empty for deactivations of expired sellers.
+func (s *ONDCService) holdDeactivations(ctx context.Context, runID, registryEnv, domain string, sellerIDs []string, active int, reason string) (string, error) {
+	ids, _ := json.Marshal(sellerIDs)
+	hold := &registryPorts.HeldDeactivation{
+		HoldID:        uuid.New().String(),
+		RunID:         runID,
+		RegistryEnv:   registryEnv,
+		Domain:        domain,
+		Status:        registryPorts.DeactivationHoldStatusHeld,
+		SellerIDs:     string(ids),
+		SellerCount:   len(sellerIDs),
+		ActiveSellers: active,
+		Reason:        reason,
+		CreatedAt:     time.Now(),
+	}
+	if err := s.holdRepo.CreateDeactivationHold(ctx, hold); err != nil {
+		return "", err
+	}
+	log.Warnf(ctx, "Held deactivation of %d sellers in %s/%s for approval (hold %s): %s", len(sellerIDs), registryEnv, domain, hold.HoldID, reason)
+	return hold.HoldID, nil
+}
+
+func (s *ONDCService) GetHeldDeactivations(registryEnv, status string, limit, page, offset int) (*registryPorts.HeldDeactivationListResponse, error) {
+	holds, err := s.holdRepo.GetDeactivationHolds(registryEnv, registryPorts.DeactivationHoldStatus(status), limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	hasMore := len(holds) > limit
+	if hasMore {
+		holds = holds[:limit] // Trim the extra record fetched for hasMore check
+	}
+
+	response := &registryPorts.HeldDeactivationListResponse{
+		RegistryEnv: registryEnv,
+		Status:      status,
+		Holds:       []registryPorts.HeldDeactivationResponse{},
+		Page: catalogPorts.PageInfo{
+			Limit:   limit,
+			Page:    page,
+			HasMore: hasMore,
+		},
+	}
+	for _, hold := range holds {
+		response.Holds = append(response.Holds, *toHeldDeactivationResponse(hold))
+	}
+	return response, nil
+}
+
+func (s *ONDCService) GetHeldDeactivation(holdID string) (*registryPorts.HeldDeactivationResponse, error) {
+	hold, err := s.holdRepo.GetDeactivationHold(holdID)
+	if err != nil {
+		return nil, err // Could be gorm.ErrRecordNotFound
+	}
+	return toHeldDeactivationResponse(*hold), nil
+}
+
+// ApproveHeldDeactivation applies held deactivations. Sellers that have been
+// seen in the registry since the hold was created, or that are no longer
+// active, are left alone. The approval takes the domain's sync lease for its
+// duration, so a *SyncAlreadyRunningError is returned while a sync of the
+// domain is running.
+func (s *ONDCService) ApproveHeldDeactivation(ctx context.Context, holdID string) (*registryPorts.HeldDeactivationResponse, error) {
+	hold, err := s.holdRepo.GetDeactivationHold(holdID)
+	if err != nil {
+		return nil, err // Could be gorm.ErrRecordNotFound
+	}
+	if hold.Status != registryPorts.DeactivationHoldStatusHeld {
+		return nil, ErrDeactivationHoldResolved
+	}
+	var sellerIDs []string
+	if err := json.Unmarshal([]byte(hold.SellerIDs), &sellerIDs); err != nil {
+		return nil, fmt.Errorf("failed to decode seller IDs of held deactivation %s: %w", holdID, err)
+	}
+
+	// A running sync may reactivate the sellers or hold them again meanwhile
+	lockHolder := "approval:" + holdID
+	if err := s.acquireSyncLocks(ctx, hold.RegistryEnv, []string{hold.Domain}, lockHolder); err != nil {
+		return nil, err
+	}
+	defer s.releaseSyncLocks(context.WithoutCancel(ctx), hold.RegistryEnv, lockHolder)
+
+	// Claim the hold first so it cannot be approved twice or discarded meanwhile
+	claimed, err := s.holdRepo.ResolveDeactivationHold(ctx, holdID, registryPorts.DeactivationHoldStatusHeld, registryPorts.DeactivationHoldStatusApproved, 0)
+	if err != nil {
+		return nil, err
+	}
+	if !claimed {
+		return nil, ErrDeactivationHoldResolved
+	}
+
+	deactivated, err := s.sellerRepo.DeactivateUnseenSellers(ctx, sellerIDs, hold.Domain, hold.RegistryEnv, hold.CreatedAt)
+	if err != nil {
+		if _, revertErr := s.holdRepo.ResolveDeactivationHold(context.WithoutCancel(ctx), holdID, registryPorts.DeactivationHoldStatusApproved, registryPorts.DeactivationHoldStatusHeld, 0); revertErr != nil {
+			log.Error(ctx, revertErr, "Failed to release held deactivations after a failed approval")
+		}
+		return nil, err
+	}
+
+	if len(deactivated) > 0 {
+		now := time.Now()
+		change := []catalogPorts.FieldChange{{Field: "active", Old: "true", New: "false"}}
+		changes := make([]catalogPorts.SellerChange, 0, len(deactivated))
+		for _, seller := range deactivated {
+			changes = append(changes, newSellerChange(hold.RunID, seller, catalogPorts.SellerChangeDeactivated, change, now))
+		}
+		if err := s.sellerRepo.InsertSellerChanges(ctx, changes); err != nil {
+			log.Error(ctx, err, "Failed to record approved seller deactivations")
+		}
+	}
+	if _, err := s.holdRepo.ResolveDeactivationHold(ctx, holdID, registryPorts.DeactivationHoldStatusApproved, registryPorts.DeactivationHoldStatusApproved, len(deactivated)); err != nil {
+		log.Error(ctx, err, "Failed to record the number of approved seller deactivations")
+	}
+	log.Infof(ctx, "Approved held deactivations %s: deactivated %d of %d sellers", holdID, len(deactivated), len(sellerIDs))
+
+	return s.GetHeldDeactivation(holdID)
+}
+
+// DiscardHeldDeactivation drops held deactivations, leaving the sellers active.
+func (s *ONDCService) DiscardHeldDeactivation(ctx context.Context, holdID string) (*registryPorts.HeldDeactivationResponse, error) {
+	if _, err := s.holdRepo.GetDeactivationHold(holdID); err != nil {
+		return nil, err // Could be gorm.ErrRecordNotFound
+	}
+	discarded, err := s.holdRepo.ResolveDeactivationHold(ctx, holdID, registryPorts.DeactivationHoldStatusHeld, registryPorts.DeactivationHoldStatusDiscarded, 0)
+	if err != nil {
+		return nil, err
+	}
+	if !discarded {
+		return nil, ErrDeactivationHoldResolved
+	}
+	return s.GetHeldDeactivation(holdID)
+}
+
+func toHeldDeactivationResponse(hold registryPorts.HeldDeactivation) *registryPorts.HeldDeactivationResponse {
+	response := &registryPorts.HeldDeactivationResponse{
+		HoldID:        hold.HoldID,
+		RunID:         hold.RunID,
+		RegistryEnv:   hold.RegistryEnv,
+		Domain:        hold.Domain,
+		Status:        string(hold.Status),
+		Reason:        hold.Reason,
+		SellerCount:   hold.SellerCount,
+		ActiveSellers: hold.ActiveSellers,
+		SellerIDs:     []string{},
+		Deactivated:   hold.Deactivated,
+		CreatedAt:     hold.CreatedAt,
+		ResolvedAt:    hold.ResolvedAt,
+	}
+	_ = json.Unmarshal([]byte(hold.SellerIDs), &response.SellerIDs)
+	return response
+}
@@ -0,0 +1,173 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	registryPorts "adapter/internal/ports/registry_sync"
+	"adapter/internal/shared/log"
+)
+
+// cancelPollInterval is how often a running job checks whether it has been
+// cancelled through another replica.
+const cancelPollInterval = 5 * time.Second
+
+var (
+	// ErrSyncJobCancelled is the cancellation cause of a job stopped through
+	// CancelSyncJob.
+	ErrSyncJobCancelled = errors.New("registry sync job cancelled")
+	// ErrSyncJobFinished is returned when cancelling a job that has already
+	// reached a terminal status.
+	ErrSyncJobFinished = errors.New("registry sync job already finished")
+)
+
+type syncJob struct {
+	cancel context.CancelCauseFunc
+	done   chan struct{}
+}
+
+// StartSyncJob queues a registry sync and runs it in the background. The
+// returned job ID is the run ID under which progress is recorded. The job
+// holds a lease on each of its domains for as long as it runs; if another job
+// holds any of them, a *SyncAlreadyRunningError is returned and nothing is
+// queued. Envs without registry configuration are rejected with
+// ErrUnknownRegistryEnv. A request with a ReplayRunID reconciles from the
+// responses that run archived instead of calling the registry; it only
+// reports what it would change and records nothing but its own run.
+func (s *ONDCService) StartSyncJob(ctx context.Context, req registryPorts.SyncRegistryRequest) (*registryPorts.SyncJobAcceptedResponse, error) {
+	if req.ReplayRunID != "" {
+		var err error
+		if req, err = s.resolveReplay(req); err != nil {
+			return nil, err
+		}
+	} else {
+		registry, err := s.Registry(req.RegistryEnv)
+		if err != nil {
+			return nil, err
+		}
+		req.LookupCriteria = resolveLookupCriteria(registry, req.LookupCriteria)
+	}
+
+	domains := uniqueDomains(req.Domains)
+	jobID := uuid.New().String()
+
+	if err := s.acquireSyncLocks(ctx, req.RegistryEnv, domains, jobID); err != nil {
+		return nil, err
+	}
+
+	run, err := s.createSyncRun(ctx, jobID, req, domains)
+	if err != nil {
+		s.releaseSyncLocks(context.WithoutCancel(ctx), req.RegistryEnv, jobID)
+		return nil, err
+	}
+
+	// The job outlives the request that started it and stops only when
+	// cancelled or when the service shuts down
+	jobCtx, cancel := context.WithCancelCause(s.shutdownCtx)
+	job := &syncJob{cancel: cancel, done: make(chan struct{})}
+
+	s.jobsMu.Lock()
+	s.jobs[run.RunID] = job
+	s.jobsMu.Unlock()
+
+	s.jobsWG.Add(1)
+	go func() {
+		defer s.jobsWG.Done()
+		defer close(job.done)
+		defer func() {
+			s.jobsMu.Lock()
+			delete(s.jobs, run.RunID)
+			s.jobsMu.Unlock()
+			cancel(nil)
+		}()
+
+		go s.watchCancelRequest(jobCtx, run.RunID, cancel)
+		go s.renewSyncLocks(jobCtx, req.RegistryEnv, domains, run.RunID, cancel)
+		s.runSync(jobCtx, run, req.RegistryEnv, domains, req.LookupCriteria)
+		s.releaseSyncLocks(context.WithoutCancel(jobCtx), req.RegistryEnv, run.RunID)
+	}()
+
+	return &registryPorts.SyncJobAcceptedResponse{
+		JobID:            run.RunID,
+		RegistryEnv:      run.RegistryEnv,
+		Status:           string(run.Status),
+		RequestedDomains: domains,
+		LookupCriteria:   req.LookupCriteria,
+		ReplayOfRunID:    req.ReplayRunID,
+		QueuedAt:         run.StartedAt,
+	}, nil
+}
+
+// CancelSyncJob requests cancellation of a queued or running job. The job is
+// stopped immediately when it runs in this process; otherwise the replica
+// running it picks the request up on its next poll.
+func (s *ONDCService) CancelSyncJob(ctx context.Context, jobID string) (*registryPorts.SyncRunResponse, error) {
+	run, err := s.runRepo.GetSyncRun(jobID)
+	if err != nil {
+		return nil, err // Could be gorm.ErrRecordNotFound
+	}
+	if run.Status.IsFinished() {
+		return nil, ErrSyncJobFinished
+	}
+
+	requested, err := s.runRepo.RequestSyncRunCancel(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if !requested {
+		return nil, ErrSyncJobFinished
+	}
+
+	s.jobsMu.Lock()
+	job, ok := s.jobs[jobID]
+	s.jobsMu.Unlock()
+	if ok {
+		job.cancel(ErrSyncJobCancelled)
+	}
+
+	return s.GetSyncRun(jobID)
+}
+
+// WaitSyncJob blocks until a job started by this process finishes or ctx is
+// done, and returns the job's final state.
+func (s *ONDCService) WaitSyncJob(ctx context.Context, jobID string) (*registryPorts.SyncRunResponse, error) {
+	s.jobsMu.Lock()
+	job, ok := s.jobs[jobID]
+	s.jobsMu.Unlock()
+
+	if ok {
+		select {
+		case <-job.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return s.GetSyncRun(jobID)
+}
+
+func (s *ONDCService) watchCancelRequest(ctx context.Context, runID string, cancel context.CancelCauseFunc) {
+	ticker := time.NewTicker(cancelPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			requested, err := s.runRepo.IsSyncRunCancelRequested(ctx, runID)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Error(ctx, err, "Failed to check registry sync job cancellation")
+				}
+				continue
+			}
+			if requested {
+				cancel(ErrSyncJobCancelled)
+				return
+			}
+		}
+	}
+}
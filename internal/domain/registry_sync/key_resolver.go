@@ -0,0 +1,141 @@
+package domain
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	catalogPorts "adapter/internal/ports/catalog_sync"
+)
+
+// ErrSigningKeyNotFound is returned when the registry has no signing key for the
+// requested subscriber and unique key ID.
+var ErrSigningKeyNotFound = errors.New("signing key not found in registry")
+
+// defaultKeyCacheSize caps the key cache when no size is configured.
+const defaultKeyCacheSize = 10000
+
+type cachedSigningKey struct {
+	cacheKey  string
+	key       string
+	err       error
+	expiresAt time.Time
+}
+
+// RegistryKeyResolver resolves a subscriber's signing public key from the keys
+// stored by registry sync, falling back to a registry lookup for keys the
+// store does not have. Keys are only resolved in the registry env the request
+// targets, so a subscriber registered in one env cannot sign requests for
+// another. Lookup results are cached so repeated requests from the same
+// caller do not hit the registry every time; keys that could not be resolved
+// are cached for the shorter negativeTTL so unsigned or forged requests cannot
+// drive a registry call each. The cache is keyed by unauthenticated header
+// values, so it holds at most maxEntries keys and evicts the least recently
+// used one first.
+type RegistryKeyResolver struct {
+	ondcService *ONDCService
+	ttl         time.Duration
+	negativeTTL time.Duration
+	maxEntries  int
+
+	mu    sync.Mutex
+	cache map[string]*list.Element
+	lru   *list.List
+}
+
+func NewRegistryKeyResolver(ondcService *ONDCService, ttl, negativeTTL time.Duration, maxEntries int) *RegistryKeyResolver {
+	if maxEntries <= 0 {
+		maxEntries = defaultKeyCacheSize
+	}
+	return &RegistryKeyResolver{
+		ondcService: ondcService,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		maxEntries:  maxEntries,
+		cache:       make(map[string]*list.Element),
+		lru:         list.New(),
+	}
+}
+
+// ResolveSigningKey resolves the key in registryEnv, or in the default registry
+// env if registryEnv is empty. Unknown registry envs return
+// ErrUnknownRegistryEnv.
+func (r *RegistryKeyResolver) ResolveSigningKey(ctx context.Context, registryEnv, subscriberID, uniqueKeyID string) (string, error) {
+	if registryEnv == "" {
+		registryEnv = r.ondcService.registryEnv
+	}
+	if _, err := r.ondcService.Registry(registryEnv); err != nil {
+		return "", err
+	}
+	cacheKey := registryEnv + "|" + subscriberID + "|" + uniqueKeyID
+
+	if cached, ok := r.cached(cacheKey); ok {
+		return cached.key, cached.err
+	}
+
+	if key, ok := r.ondcService.storedSigningKey(ctx, registryEnv, subscriberID, uniqueKeyID); ok {
+		return key, nil
+	}
+
+	subscribers, err := r.ondcService.LookupSubscriber(ctx, registryEnv, subscriberID, uniqueKeyID)
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", err
+		}
+		err = errors.Join(ErrSigningKeyNotFound, err)
+	} else {
+		for _, sub := range subscribers {
+			if sub.SubscriberID != subscriberID || sub.UkID != uniqueKeyID || sub.SigningKey == "" {
+				continue
+			}
+			// Only subscribed participants may sign requests
+			if r.ondcService.lifecycleFor(sub.Status) != catalogPorts.LifecycleActive {
+				continue
+			}
+			r.store(cachedSigningKey{cacheKey: cacheKey, key: sub.SigningKey, expiresAt: time.Now().Add(r.ttl)})
+			return sub.SigningKey, nil
+		}
+		err = ErrSigningKeyNotFound
+	}
+
+	if r.negativeTTL > 0 {
+		r.store(cachedSigningKey{cacheKey: cacheKey, err: err, expiresAt: time.Now().Add(r.negativeTTL)})
+	}
+	return "", err
+}
+
+// cached returns an unexpired cache entry and marks it as recently used.
+func (r *RegistryKeyResolver) cached(cacheKey string) (cachedSigningKey, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	elem, ok := r.cache[cacheKey]
+	if !ok {
+		return cachedSigningKey{}, false
+	}
+	entry := elem.Value.(cachedSigningKey)
+	if !time.Now().Before(entry.expiresAt) {
+		r.lru.Remove(elem)
+		delete(r.cache, cacheKey)
+		return cachedSigningKey{}, false
+	}
+	r.lru.MoveToFront(elem)
+	return entry, true
+}
+
+func (r *RegistryKeyResolver) store(entry cachedSigningKey) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if elem, ok := r.cache[entry.cacheKey]; ok {
+		elem.Value = entry
+		r.lru.MoveToFront(elem)
+		return
+	}
+	r.cache[entry.cacheKey] = r.lru.PushFront(entry)
+	for r.lru.Len() > r.maxEntries {
+		oldest := r.lru.Back()
+		r.lru.Remove(oldest)
+		delete(r.cache, oldest.Value.(cachedSigningKey).cacheKey)
+	}
+}
@@ -0,0 +1,166 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	catalogPorts "adapter/internal/ports/catalog_sync"
+	registryPorts "adapter/internal/ports/registry_sync"
+	"adapter/internal/shared/log"
+)
+
+// seenSubscribers collects the subscribers whose keys a run has recorded, so
+// key rotation can be decided once every domain has been looked up.
+type seenSubscribers struct {
+	mu  sync.Mutex
+	ids map[string]struct{}
+}
+
+func newSeenSubscribers() *seenSubscribers {
+	return &seenSubscribers{ids: make(map[string]struct{})}
+}
+
+func (s *seenSubscribers) add(subscriberID string) {
+	s.mu.Lock()
+	s.ids[subscriberID] = struct{}{}
+	s.mu.Unlock()
+}
+
+func (s *seenSubscribers) list() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids := make([]string, 0, len(s.ids))
+	for id := range s.ids {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// recordSubscriberKeys stores the keys published by the subscribers of a
+// lookup, along with the lifecycle of the subscriber, and adds the subscribers
+// to seen.
+func (s *ONDCService) recordSubscriberKeys(ctx context.Context, registryEnv string, subscribers ONDCLookupResponse, seen *seenSubscribers) {
+	now := time.Now()
+	recorded := make(map[string]int, len(subscribers))
+	keys := make([]registryPorts.SubscriberKey, 0, len(subscribers))
+	for _, sub := range subscribers {
+		if sub.SubscriberID == "" || sub.UkID == "" || sub.SigningKey == "" {
+			continue
+		}
+		// A subscriber listed under several domains publishes the same key in
+		// each; it is trusted if it is active in any of them
+		lifecycle := string(s.lifecycleFor(sub.Status))
+		id := sub.SubscriberID + "|" + sub.UkID
+		if i, ok := recorded[id]; ok {
+			if lifecycle == string(catalogPorts.LifecycleActive) {
+				keys[i].Lifecycle = lifecycle
+			}
+			continue
+		}
+		recorded[id] = len(keys)
+
+		validFrom, _ := parseValidityDate(sub.ValidFrom)
+		validUntil, _ := parseValidityDate(sub.ValidUntil)
+		keys = append(keys, registryPorts.SubscriberKey{
+			RegistryEnv:      registryEnv,
+			SubscriberID:     sub.SubscriberID,
+			UkID:             sub.UkID,
+			SigningPublicKey: sub.SigningKey,
+			EncrPublicKey:    sub.EncryptionKey,
+			Lifecycle:        lifecycle,
+			ValidFrom:        validFrom,
+			ValidUntil:       validUntil,
+			FirstSeenAt:      now,
+			LastSeenAt:       now,
+		})
+	}
+
+	if err := s.keyRepo.UpsertSubscriberKeys(ctx, keys); err != nil {
+		log.Error(ctx, err, "Failed to record subscriber keys")
+		return
+	}
+	for _, key := range keys {
+		seen.add(key.SubscriberID)
+	}
+}
+
+// markRotatedSubscriberKeys marks the keys of the subscribers seen in a run
+// that were not published again since the run started as rotated. A
+// subscriber may publish different keys in different domains, so this only
+// runs once every domain of the run has been looked up.
+func (s *ONDCService) markRotatedSubscriberKeys(ctx context.Context, registryEnv string, seen *seenSubscribers, seenSince time.Time) {
+	if err := s.keyRepo.MarkSubscriberKeysRotated(ctx, registryEnv, seen.list(), seenSince); err != nil {
+		log.Error(ctx, err, "Failed to mark rotated subscriber keys")
+	}
+}
+
+// GetSubscriberKeys returns the current and historical keys of a subscriber,
+// or gorm.ErrRecordNotFound if no key has been synced for it.
+func (s *ONDCService) GetSubscriberKeys(registryEnv, subscriberID string) (*registryPorts.SubscriberKeysResponse, error) {
+	keys, err := s.keyRepo.GetSubscriberKeys(registryEnv, subscriberID)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	now := time.Now()
+	response := &registryPorts.SubscriberKeysResponse{
+		SubscriberID: subscriberID,
+		RegistryEnv:  registryEnv,
+		Keys:         []registryPorts.SubscriberKeyResponse{},
+	}
+	for _, key := range keys {
+		response.Keys = append(response.Keys, registryPorts.SubscriberKeyResponse{
+			UkID:             key.UkID,
+			SigningPublicKey: key.SigningPublicKey,
+			EncrPublicKey:    key.EncrPublicKey,
+			Lifecycle:        key.Lifecycle,
+			ValidFrom:        key.ValidFrom,
+			ValidUntil:       key.ValidUntil,
+			Current:          isKeyCurrent(key, now),
+			FirstSeenAt:      key.FirstSeenAt,
+			LastSeenAt:       key.LastSeenAt,
+			RotatedAt:        key.RotatedAt,
+		})
+	}
+	return response, nil
+}
+
+// storedSigningKey returns a current signing key from the key store.
+func (s *ONDCService) storedSigningKey(ctx context.Context, registryEnv, subscriberID, ukID string) (string, bool) {
+	key, err := s.keyRepo.GetSubscriberKey(ctx, registryEnv, subscriberID, ukID)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Error(ctx, err, "Failed to read subscriber key store")
+		}
+		return "", false
+	}
+	if !isKeyCurrent(*key, time.Now()) {
+		return "", false
+	}
+	return key.SigningPublicKey, true
+}
+
+// isKeyCurrent reports whether a key may be trusted: it has not been rotated,
+// is inside its validity window and belongs to an active subscriber. Keys
+// stored before lifecycles were recorded are not trusted until synced again.
+func isKeyCurrent(key registryPorts.SubscriberKey, now time.Time) bool {
+	if key.RotatedAt != nil || key.Lifecycle != string(catalogPorts.LifecycleActive) {
+		return false
+	}
+	if key.ValidFrom != nil && now.Before(*key.ValidFrom) {
+		return false
+	}
+	if key.ValidUntil != nil && !now.Before(*key.ValidUntil) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,108 @@
+package domain
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	catalogPorts "adapter/internal/ports/catalog_sync"
+)
+
+// lifecycleRank orders lifecycles from most to least preferred for a primary
+// registry entry.
+var lifecycleRank = map[catalogPorts.Lifecycle]int{
+	catalogPorts.LifecycleActive:   0,
+	catalogPorts.LifecyclePending:  1,
+	catalogPorts.LifecycleInactive: 2,
+}
+
+// groupSubscriberEntries collects the registry entries of each subscriber in a
+// domain. The registry returns one entry per city and key of a subscriber.
+func groupSubscriberEntries(subs ONDCLookupResponse, domain string) map[string][]Subscriber {
+	entries := make(map[string][]Subscriber)
+	for _, sub := range subs {
+		// A v2 entry lists every domain of the subscriber
+		if sub.Domain != "" && sub.Domain != domain {
+			continue
+		}
+		entries[sub.SubscriberID] = append(entries[sub.SubscriberID], sub)
+	}
+	return entries
+}
+
+// sortSubscriberEntries orders a subscriber's entries so that the first one is
+// its primary entry, regardless of the order the registry returned them in:
+// the best lifecycle first, then entries inside their validity window, then
+// by ukId and city.
+func (s *ONDCService) sortSubscriberEntries(entries []Subscriber, now time.Time) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if ra, rb := lifecycleRank[s.lifecycleFor(a.Status)], lifecycleRank[s.lifecycleFor(b.Status)]; ra != rb {
+			return ra < rb
+		}
+		if va, vb := entryWithinValidity(a, now), entryWithinValidity(b, now); va != vb {
+			return va
+		}
+		if a.UkID != b.UkID {
+			return a.UkID < b.UkID
+		}
+		return a.City < b.City
+	})
+}
+
+func entryWithinValidity(sub Subscriber, now time.Time) bool {
+	validFrom, _ := parseValidityDate(sub.ValidFrom)
+	validUntil, _ := parseValidityDate(sub.ValidUntil)
+	return catalogPorts.Seller{ValidFrom: validFrom, ValidUntil: validUntil}.WithinValidity(now)
+}
+
+// entriesHash fingerprints every registry entry of a subscriber, so a change
+// to any of them is detected, not just to the primary one.
+func entriesHash(entries []Subscriber) string {
+	raw, _ := json.Marshal(entries)
+	return contentHash(raw)
+}
+
+// sellerLocations lists the cities a seller's registry entries cover. The
+// locations of the primary entry are marked primary.
+func sellerLocations(seller catalogPorts.Seller, entries []Subscriber) []catalogPorts.SellerLocation {
+	var locations []catalogPorts.SellerLocation
+	seen := make(map[string]bool)
+	for i, sub := range entries {
+		cities := sub.CityCodes
+		if sub.City != "" {
+			cities = append([]string{sub.City}, cities...)
+		}
+		if len(cities) == 0 {
+			cities = []string{""}
+		}
+
+		validFrom, _ := parseValidityDate(sub.ValidFrom)
+		validUntil, _ := parseValidityDate(sub.ValidUntil)
+		subscriberURL := sub.SubscriberURL
+		if subscriberURL == "" {
+			subscriberURL = sub.SubscriberID
+		}
+		for _, city := range cities {
+			key := city + "|" + sub.UkID
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			locations = append(locations, catalogPorts.SellerLocation{
+				SellerID:      seller.SellerID,
+				Domain:        seller.Domain,
+				RegistryEnv:   seller.RegistryEnv,
+				City:          city,
+				UkID:          sub.UkID,
+				Country:       sub.Country,
+				Status:        sub.Status,
+				SubscriberURL: subscriberURL,
+				ValidFrom:     validFrom,
+				ValidUntil:    validUntil,
+				Primary:       i == 0,
+			})
+		}
+	}
+	return locations
+}
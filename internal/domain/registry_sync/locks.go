@@ -0,0 +1,91 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	registryPorts "adapter/internal/ports/registry_sync"
+	"adapter/internal/shared/log"
+)
+
+// ErrSyncLockLost is the cancellation cause of a job whose sync lease was
+// taken over, typically after it failed to renew the lease in time.
+var ErrSyncLockLost = errors.New("registry sync lock lost")
+
+// SyncAlreadyRunningError is returned when another job holds the sync lease on
+// one or more of the requested domains.
+type SyncAlreadyRunningError struct {
+	Running []registryPorts.RunningSyncJob
+}
+
+func (e *SyncAlreadyRunningError) Error() string {
+	domains := make([]string, 0, len(e.Running))
+	for _, running := range e.Running {
+		domains = append(domains, fmt.Sprintf("%s (job %s)", running.Domain, running.JobID))
+	}
+	return "registry sync already running for " + strings.Join(domains, ", ")
+}
+
+func (s *ONDCService) acquireSyncLocks(ctx context.Context, registryEnv string, domains []string, jobID string) error {
+	conflicts, err := s.lockRepo.AcquireSyncLocks(ctx, registryEnv, domains, jobID, s.lockTTL)
+	if err != nil {
+		return err
+	}
+	if len(conflicts) == 0 {
+		return nil
+	}
+
+	alreadyRunning := &SyncAlreadyRunningError{}
+	for _, lock := range conflicts {
+		alreadyRunning.Running = append(alreadyRunning.Running, registryPorts.RunningSyncJob{
+			Domain:         lock.Domain,
+			JobID:          lock.Holder,
+			LeaseExpiresAt: lock.ExpiresAt,
+		})
+	}
+	return alreadyRunning
+}
+
+// renewSyncLocks keeps a job's leases on its domains alive until ctx is done.
+// The job is cancelled as soon as any lease was taken over, or once renewals
+// have kept failing for longer than the lease TTL, since another replica may
+// have acquired the leases by then.
+func (s *ONDCService) renewSyncLocks(ctx context.Context, registryEnv string, domains []string, jobID string, cancel context.CancelCauseFunc) {
+	ticker := time.NewTicker(s.lockTTL / 3)
+	defer ticker.Stop()
+
+	lastRenewed := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			renewed, err := s.lockRepo.RenewSyncLocks(ctx, registryEnv, jobID, s.lockTTL)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				log.Error(ctx, err, "Failed to renew registry sync lock")
+				if time.Since(lastRenewed) >= s.lockTTL {
+					cancel(fmt.Errorf("%w: renewals failing since %s: %w", ErrSyncLockLost, lastRenewed.Format(time.RFC3339), err))
+					return
+				}
+				continue
+			}
+			if renewed < int64(len(domains)) {
+				cancel(fmt.Errorf("%w: renewed %d of %d domain leases", ErrSyncLockLost, renewed, len(domains)))
+				return
+			}
+			lastRenewed = time.Now()
+		}
+	}
+}
+
+func (s *ONDCService) releaseSyncLocks(ctx context.Context, registryEnv, jobID string) {
+	if err := s.lockRepo.ReleaseSyncLocks(ctx, registryEnv, jobID); err != nil {
+		log.Error(ctx, err, "Failed to release registry sync lock")
+	}
+}
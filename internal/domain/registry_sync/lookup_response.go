@@ -0,0 +1,177 @@
+package domain
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Registry lookup response formats. LookupFormatAuto detects the format of each
+// entry from its fields.
+const (
+	LookupFormatAuto   = "auto"
+	LookupFormatLegacy = "legacy"
+	LookupFormatV2     = "v2"
+)
+
+// lookupEntryV2 is a subscriber as returned by the v2.0 lookup: keys and
+// per-domain network participant details are nested instead of flattened.
+type lookupEntryV2 struct {
+	SubscriberID       string                 `json:"subscriber_id"`
+	UkID               string                 `json:"ukId"`
+	BrID               string                 `json:"br_id"`
+	Country            string                 `json:"country"`
+	City               string                 `json:"city"`
+	Status             string                 `json:"status"`
+	Created            string                 `json:"created"`
+	Updated            string                 `json:"updated"`
+	KeyPair            *lookupKeyV2           `json:"key_pair"`
+	Keys               []lookupKeyV2          `json:"keys"`
+	NetworkParticipant []networkParticipantV2 `json:"network_participant"`
+}
+
+type lookupKeyV2 struct {
+	UkID             string `json:"ukId"`
+	SigningPublicKey string `json:"signing_public_key"`
+	EncrPublicKey    string `json:"encr_public_key"`
+	EncryptionKey    string `json:"encryption_public_key"`
+	ValidFrom        string `json:"valid_from"`
+	ValidUntil       string `json:"valid_until"`
+}
+
+type networkParticipantV2 struct {
+	SubscriberURL string   `json:"subscriber_url"`
+	Domain        string   `json:"domain"`
+	Type          string   `json:"type"`
+	MSN           bool     `json:"msn"`
+	CityCode      []string `json:"city_code"`
+}
+
+// participantTypes maps v2 participant types to the lookup types used by the
+// legacy format and in lookup criteria.
+var participantTypes = map[string]string{
+	"sellerapp": "BPP",
+	"buyerapp":  "BAP",
+	"gateway":   "BG",
+}
+
+// decodeLookupResponse parses a registry lookup response into one Subscriber
+// per subscriber, domain and city, whichever format the registry answered in.
+func decodeLookupResponse(body []byte, format string) (ONDCLookupResponse, error) {
+	var entries []json.RawMessage
+	if err := json.Unmarshal(body, &entries); err != nil {
+		return nil, fmt.Errorf("failed to decode registry lookup response: %w", err)
+	}
+
+	response := make(ONDCLookupResponse, 0, len(entries))
+	for _, entry := range entries {
+		entryFormat := format
+		if entryFormat == "" || entryFormat == LookupFormatAuto {
+			entryFormat = detectLookupFormat(entry)
+		}
+
+		if entryFormat == LookupFormatV2 {
+			var v2 lookupEntryV2
+			if err := json.Unmarshal(entry, &v2); err != nil {
+				return nil, fmt.Errorf("failed to decode v2 registry lookup entry: %w", err)
+			}
+			response = append(response, v2.subscribers()...)
+			continue
+		}
+
+		var sub Subscriber
+		if err := json.Unmarshal(entry, &sub); err != nil {
+			return nil, fmt.Errorf("failed to decode registry lookup entry: %w", err)
+		}
+		response = append(response, sub)
+	}
+	return response, nil
+}
+
+// detectLookupFormat recognises v2 entries by their nested network
+// participants.
+func detectLookupFormat(entry json.RawMessage) string {
+	if bytes.Contains(entry, []byte(`"network_participant"`)) {
+		return LookupFormatV2
+	}
+	return LookupFormatLegacy
+}
+
+// subscribers flattens a v2 entry into one Subscriber per network participant
+// and city, carrying the entry's current key, the way the legacy lookup lists
+// them. groupSubscriberEntries merges them back into one seller per domain.
+func (e lookupEntryV2) subscribers() []Subscriber {
+	key := e.currentKey()
+	base := Subscriber{
+		SubscriberID:  e.SubscriberID,
+		UkID:          e.UkID,
+		BrID:          e.BrID,
+		Country:       e.Country,
+		City:          e.City,
+		SigningKey:    key.SigningPublicKey,
+		EncryptionKey: key.EncrPublicKey,
+		Status:        e.Status,
+		ValidFrom:     key.ValidFrom,
+		ValidUntil:    key.ValidUntil,
+		Created:       e.Created,
+		Updated:       e.Updated,
+	}
+	if base.UkID == "" {
+		base.UkID = key.UkID
+	}
+	if base.EncryptionKey == "" {
+		base.EncryptionKey = key.EncryptionKey
+	}
+
+	subscribers := make([]Subscriber, 0, len(e.NetworkParticipant))
+	for _, participant := range e.NetworkParticipant {
+		sub := base
+		sub.Domain = participant.Domain
+		sub.Type = participantType(participant.Type)
+		sub.SubscriberURL = participantURL(e.SubscriberID, participant.SubscriberURL)
+		sub.MSN = participant.MSN
+		if len(participant.CityCode) == 0 {
+			subscribers = append(subscribers, sub)
+			continue
+		}
+		for _, city := range participant.CityCode {
+			sub.City = city
+			subscribers = append(subscribers, sub)
+		}
+	}
+	return subscribers
+}
+
+// currentKey returns the key published under the entry's ukId, falling back
+// to the key pair or the first listed key.
+func (e lookupEntryV2) currentKey() lookupKeyV2 {
+	for _, key := range e.Keys {
+		if key.UkID == e.UkID {
+			return key
+		}
+	}
+	if e.KeyPair != nil {
+		return *e.KeyPair
+	}
+	if len(e.Keys) > 0 {
+		return e.Keys[0]
+	}
+	return lookupKeyV2{}
+}
+
+func participantType(value string) string {
+	if t, ok := participantTypes[strings.ToLower(value)]; ok {
+		return t
+	}
+	return strings.ToUpper(value)
+}
+
+// participantURL resolves a participant's callback URL. The v2 lookup may
+// give it as a path relative to the subscriber ID.
+func participantURL(subscriberID, subscriberURL string) string {
+	if subscriberURL == "" || strings.Contains(subscriberURL, "://") {
+		return subscriberURL
+	}
+	return "https://" + subscriberID + "/" + strings.TrimPrefix(subscriberURL, "/")
+}
@@ -0,0 +1,126 @@
+package domain
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDecodeLookupResponse(t *testing.T) {
+	legacy := `{
+		"subscriber_id": "grocer-one.example.com",
+		"ukId": "k-g1",
+		"br_id": "br-grocer-one",
+		"type": "BPP",
+		"domain": "ONDC:RET10",
+		"country": "IND",
+		"city": "std:080",
+		"subscriber_url": "https://grocer-one.example.com/ondc",
+		"signing_public_key": "sign-g1",
+		"encr_public_key": "encr-g1",
+		"status": "SUBSCRIBED",
+		"valid_from": "2024-01-01T00:00:00.000Z",
+		"valid_until": "2030-01-01T00:00:00.000Z",
+		"created": "2024-01-01T00:00:00.000Z",
+		"updated": "2024-02-01T00:00:00.000Z"
+	}`
+	v2 := `{
+		"subscriber_id": "grocer-one.example.com",
+		"ukId": "k-g1",
+		"br_id": "br-grocer-one",
+		"country": "IND",
+		"status": "SUBSCRIBED",
+		"created": "2024-01-01T00:00:00.000Z",
+		"updated": "2024-02-01T00:00:00.000Z",
+		"keys": [
+			{"ukId": "k-old", "signing_public_key": "sign-old", "encr_public_key": "encr-old", "valid_from": "2023-01-01T00:00:00.000Z", "valid_until": "2024-01-01T00:00:00.000Z"},
+			{"ukId": "k-g1", "signing_public_key": "sign-g1", "encr_public_key": "encr-g1", "valid_from": "2024-01-01T00:00:00.000Z", "valid_until": "2030-01-01T00:00:00.000Z"}
+		],
+		"network_participant": [
+			{"subscriber_url": "/ondc", "domain": "ONDC:RET10", "type": "sellerApp", "city_code": ["std:080", "std:011"]},
+			{"subscriber_url": "https://grocer-one.example.com/ret11", "domain": "ONDC:RET11", "type": "sellerApp", "msn": true}
+		]
+	}`
+
+	grocer := Subscriber{
+		SubscriberID:  "grocer-one.example.com",
+		UkID:          "k-g1",
+		BrID:          "br-grocer-one",
+		Type:          "BPP",
+		Domain:        "ONDC:RET10",
+		Country:       "IND",
+		City:          "std:080",
+		SubscriberURL: "https://grocer-one.example.com/ondc",
+		SigningKey:    "sign-g1",
+		EncryptionKey: "encr-g1",
+		Status:        "SUBSCRIBED",
+		ValidFrom:     "2024-01-01T00:00:00.000Z",
+		ValidUntil:    "2030-01-01T00:00:00.000Z",
+		Created:       "2024-01-01T00:00:00.000Z",
+		Updated:       "2024-02-01T00:00:00.000Z",
+	}
+	in := func(city string) Subscriber {
+		sub := grocer
+		sub.City = city
+		return sub
+	}
+	ret11 := grocer
+	ret11.Domain = "ONDC:RET11"
+	ret11.City = ""
+	ret11.SubscriberURL = "https://grocer-one.example.com/ret11"
+	ret11.MSN = true
+
+	tests := []struct {
+		name    string
+		body    string
+		format  string
+		want    ONDCLookupResponse
+		wantErr bool
+	}{
+		{name: "legacy", body: "[" + legacy + "]", format: LookupFormatLegacy, want: ONDCLookupResponse{grocer}},
+		{name: "legacy detected", body: "[" + legacy + "]", format: LookupFormatAuto, want: ONDCLookupResponse{grocer}},
+		{name: "v2", body: "[" + v2 + "]", format: LookupFormatV2, want: ONDCLookupResponse{grocer, in("std:011"), ret11}},
+		{name: "v2 detected", body: "[" + v2 + "]", format: LookupFormatAuto, want: ONDCLookupResponse{grocer, in("std:011"), ret11}},
+		{name: "mixed formats detected per entry", body: "[" + legacy + "," + v2 + "]", format: "", want: ONDCLookupResponse{grocer, grocer, in("std:011"), ret11}},
+		{
+			name:   "v2 key pair and encryption key",
+			format: LookupFormatAuto,
+			body: `[{
+				"subscriber_id": "foodbox.example.com",
+				"status": "INITIATED",
+				"key_pair": {"ukId": "k-fb", "signing_public_key": "sign-fb", "encryption_public_key": "encr-fb"},
+				"network_participant": [{"domain": "ONDC:RET11", "type": "buyerApp"}]
+			}]`,
+			want: ONDCLookupResponse{{
+				SubscriberID:  "foodbox.example.com",
+				UkID:          "k-fb",
+				Type:          "BAP",
+				Domain:        "ONDC:RET11",
+				SigningKey:    "sign-fb",
+				EncryptionKey: "encr-fb",
+				Status:        "INITIATED",
+			}},
+		},
+		{name: "empty", body: "[]", format: LookupFormatAuto, want: ONDCLookupResponse{}},
+		{name: "not an array", body: `{"error": "not found"}`, format: LookupFormatAuto, wantErr: true},
+		{name: "malformed v2 entry", body: `[{"network_participant": "ONDC:RET10"}]`, format: LookupFormatAuto, wantErr: true},
+		{name: "malformed legacy entry", body: `[{"subscriber_id": 42}]`, format: LookupFormatLegacy, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeLookupResponse([]byte(tt.body), tt.format)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("decoded %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decoded\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
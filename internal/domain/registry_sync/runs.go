@@ -0,0 +1,199 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	catalogPorts "adapter/internal/ports/catalog_sync"
+	registryPorts "adapter/internal/ports/registry_sync"
+	"adapter/internal/shared/log"
+)
+
+func (s *ONDCService) createSyncRun(ctx context.Context, runID string, req registryPorts.SyncRegistryRequest, domains []string) (*registryPorts.RegistrySyncRun, error) {
+	triggerSource := req.TriggerSource
+	if triggerSource == "" {
+		triggerSource = registryPorts.TriggerSourceAPI
+	}
+	requestedDomains, _ := json.Marshal(domains)
+	lookupCriteria, _ := json.Marshal(req.LookupCriteria)
+
+	run := &registryPorts.RegistrySyncRun{
+		RunID:            runID,
+		RegistryEnv:      req.RegistryEnv,
+		TriggerSource:    triggerSource,
+		Status:           registryPorts.SyncRunStatusQueued,
+		RequestedDomains: string(requestedDomains),
+		LookupCriteria:   string(lookupCriteria),
+		DomainsTotal:     len(domains),
+		StartedAt:        time.Now(),
+	}
+	if req.ReplayRunID != "" {
+		run.ReplayOfRunID = &req.ReplayRunID
+	}
+	if err := s.runRepo.CreateSyncRun(ctx, run); err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+func (s *ONDCService) recordDomainRun(ctx context.Context, run *registryPorts.RegistrySyncRun, domain string, startedAt time.Time, summary *registryPorts.DomainSyncSummary, syncErr error) {
+	runDomain := &registryPorts.RegistrySyncRunDomain{
+		RunID:       run.RunID,
+		Domain:      domain,
+		RegistryEnv: run.RegistryEnv,
+		Status:      registryPorts.DomainSyncStatusSucceeded,
+		StartedAt:   startedAt,
+		FinishedAt:  time.Now(),
+	}
+	if summary != nil {
+		raw, _ := json.Marshal(summary)
+		rawSummary := string(raw)
+		runDomain.Summary = &rawSummary
+	}
+	if syncErr != nil {
+		errMsg := syncErr.Error()
+		runDomain.Status = registryPorts.DomainSyncStatusFailed
+		runDomain.Error = &errMsg
+	}
+
+	if err := s.runRepo.SaveSyncRunDomain(ctx, runDomain); err != nil {
+		log.Error(ctx, err, "Failed to record registry sync domain result")
+	}
+
+	sellersProcessed := 0
+	if summary != nil {
+		sellersProcessed = summary.TotalSellersInRegistry
+	}
+	if err := s.runRepo.IncrementSyncRunProgress(ctx, run.RunID, sellersProcessed); err != nil {
+		log.Error(ctx, err, "Failed to record registry sync progress")
+	}
+}
+
+func (s *ONDCService) finishSyncRun(ctx context.Context, run *registryPorts.RegistrySyncRun, total, failed int, runErr error) {
+	status := registryPorts.SyncRunStatusCompleted
+	switch {
+	case errors.Is(runErr, ErrSyncJobCancelled):
+		status = registryPorts.SyncRunStatusCancelled
+	case runErr != nil || (total > 0 && failed == total):
+		status = registryPorts.SyncRunStatusFailed
+	case failed > 0:
+		status = registryPorts.SyncRunStatusPartial
+	}
+
+	var errMsg *string
+	if runErr != nil {
+		msg := runErr.Error()
+		errMsg = &msg
+	}
+
+	if err := s.runRepo.FinishSyncRun(ctx, run.RunID, status, time.Now(), errMsg); err != nil {
+		log.Error(ctx, err, "Failed to record registry sync run completion")
+	}
+}
+
+func (s *ONDCService) GetSyncRuns(registryEnv string, limit, page, offset int) (*registryPorts.SyncRunListResponse, error) {
+	runs, err := s.runRepo.GetSyncRuns(registryEnv, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	hasMore := len(runs) > limit
+	if hasMore {
+		runs = runs[:limit] // Trim the extra record fetched for hasMore check
+	}
+
+	response := &registryPorts.SyncRunListResponse{
+		RegistryEnv: registryEnv,
+		Runs:        []registryPorts.SyncRunResponse{},
+		Page: catalogPorts.PageInfo{
+			Limit:   limit,
+			Page:    page,
+			HasMore: hasMore,
+		},
+	}
+	for _, run := range runs {
+		response.Runs = append(response.Runs, *toSyncRunResponse(run))
+	}
+	return response, nil
+}
+
+func (s *ONDCService) GetSyncRun(runID string) (*registryPorts.SyncRunResponse, error) {
+	run, err := s.runRepo.GetSyncRun(runID)
+	if err != nil {
+		return nil, err // Could be gorm.ErrRecordNotFound
+	}
+	return toSyncRunResponse(*run), nil
+}
+
+// GetDomainSyncStatuses reports, per domain, the latest sync attempt and the
+// latest successful sync in the registry env.
+func (s *ONDCService) GetDomainSyncStatuses(registryEnv string) ([]registryPorts.DomainLastSyncResponse, error) {
+	latest, err := s.runRepo.GetLatestDomainRuns(registryEnv, "")
+	if err != nil {
+		return nil, err
+	}
+	successes, err := s.runRepo.GetLatestDomainRuns(registryEnv, registryPorts.DomainSyncStatusSucceeded)
+	if err != nil {
+		return nil, err
+	}
+
+	successByDomain := make(map[string]registryPorts.RegistrySyncRunDomain)
+	for _, success := range successes {
+		successByDomain[success.Domain] = success
+	}
+
+	statuses := []registryPorts.DomainLastSyncResponse{}
+	for _, runDomain := range latest {
+		status := registryPorts.DomainLastSyncResponse{
+			Domain:     runDomain.Domain,
+			LastRunID:  runDomain.RunID,
+			LastStatus: string(runDomain.Status),
+			LastRunAt:  runDomain.FinishedAt,
+			LastError:  runDomain.Error,
+		}
+		if success, ok := successByDomain[runDomain.Domain]; ok {
+			status.LastSuccessRunID = &success.RunID
+			status.LastSuccessAt = &success.FinishedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+func toSyncRunResponse(run registryPorts.RegistrySyncRun) *registryPorts.SyncRunResponse {
+	response := &registryPorts.SyncRunResponse{
+		RunID:            run.RunID,
+		RegistryEnv:      run.RegistryEnv,
+		TriggerSource:    string(run.TriggerSource),
+		Status:           string(run.Status),
+		RequestedDomains: []string{},
+		Progress: registryPorts.SyncJobProgress{
+			DomainsTotal:     run.DomainsTotal,
+			DomainsDone:      run.DomainsDone,
+			SellersProcessed: run.SellersProcessed,
+		},
+		CancelRequested: run.CancelRequested,
+		ReplayOfRunID:   run.ReplayOfRunID,
+		StartedAt:       run.StartedAt,
+		FinishedAt:      run.FinishedAt,
+		Error:           run.Error,
+	}
+	_ = json.Unmarshal([]byte(run.RequestedDomains), &response.RequestedDomains)
+	_ = json.Unmarshal([]byte(run.LookupCriteria), &response.LookupCriteria)
+
+	for _, runDomain := range run.Domains {
+		if runDomain.Summary != nil {
+			var summary registryPorts.DomainSyncSummary
+			if err := json.Unmarshal([]byte(*runDomain.Summary), &summary); err == nil {
+				response.Domains = append(response.Domains, summary)
+				response.DeactivationsHeld = response.DeactivationsHeld || summary.DeactivationsHeld
+			}
+		}
+		if runDomain.Error != nil {
+			response.Errors = append(response.Errors, registryPorts.DomainSyncError{Domain: runDomain.Domain, Error: *runDomain.Error})
+		}
+	}
+	return response
+}
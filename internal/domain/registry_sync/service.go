@@ -2,208 +2,721 @@ package domain
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"adapter/internal/config"
-	registryPorts "adapter/internal/ports/registry_sync"
 	catalogPorts "adapter/internal/ports/catalog_sync"
+	registryPorts "adapter/internal/ports/registry_sync"
 	"adapter/internal/shared/crypto"
 	"adapter/internal/shared/log"
 	"github.com/go-resty/resty/v2"
 )
 
 type ONDCLookupRequest struct {
-	Country string `json:"country"`
-	Type    string `json:"type"`
-	Domain  string `json:"domain"`
+	Country      string `json:"country,omitempty"`
+	City         string `json:"city,omitempty"`
+	Type         string `json:"type,omitempty"`
+	Domain       string `json:"domain,omitempty"`
+	SubscriberID string `json:"subscriber_id,omitempty"`
+	UkID         string `json:"ukId,omitempty"`
 }
 
+// Subscriber is a registry subscriber in one domain. Lookup responses in the
+// v2 format are flattened into this shape by decodeLookupResponse.
 type Subscriber struct {
-	SubscriberID  string `json:"subscriber_id"`
-	UkID          string `json:"ukId"`
-	BrID          string `json:"br_id"`
-	Domain        string `json:"domain"`
-	Country       string `json:"country"`
-	City          string `json:"city"`
-	SigningKey    string `json:"signing_public_key"`
-	EncryptionKey string `json:"encr_public_key"`
-	Status        string `json:"status"`
-	ValidFrom     string `json:"valid_from"`
-	ValidUntil    string `json:"valid_until"`
-	Created       string `json:"created"`
-	Updated       string `json:"updated"`
+	SubscriberID  string   `json:"subscriber_id"`
+	UkID          string   `json:"ukId"`
+	BrID          string   `json:"br_id"`
+	Type          string   `json:"type,omitempty"`
+	Domain        string   `json:"domain"`
+	Country       string   `json:"country"`
+	City          string   `json:"city"`
+	SubscriberURL string   `json:"subscriber_url,omitempty"`
+	MSN           bool     `json:"msn,omitempty"`
+	CityCodes     []string `json:"city_code,omitempty"`
+	SigningKey    string   `json:"signing_public_key"`
+	EncryptionKey string   `json:"encr_public_key"`
+	Status        string   `json:"status"`
+	ValidFrom     string   `json:"valid_from"`
+	ValidUntil    string   `json:"valid_until"`
+	Created       string   `json:"created"`
+	Updated       string   `json:"updated"`
 }
 
 type ONDCLookupResponse []Subscriber
 
+// ErrUnknownRegistryEnv is returned for a registry env that has no registry
+// configuration.
+var ErrUnknownRegistryEnv = errors.New("unknown registry env")
+
+// NewSellerPolicyApplier applies default BAP policies to sellers that a sync
+// has just discovered.
+type NewSellerPolicyApplier interface {
+	ApplyPolicyTemplatesToNewSellers(registryEnv, domain string, sellerIDs []string) (int, error)
+}
+
 type ONDCService struct {
-	client       *resty.Client
-	crypto       *crypto.ONDCCrypto
-	sellerRepo   catalogPorts.SellerRepository
-	domains      []string
-	registryURL  string
-	privateKey   string
-	subscriberID string
-	uniqueKeyID  string
-	registryEnv  string
-}
-
-func NewONDCService(sellerRepo catalogPorts.SellerRepository, cfg *config.Config) *ONDCService {
+	client        *resty.Client
+	crypto        *crypto.ONDCCrypto
+	retry         retryPolicy
+	breakers      map[string]*circuitBreaker
+	sellerRepo    catalogPorts.SellerRepository
+	runRepo       registryPorts.SyncRunRepository
+	lockRepo      registryPorts.SyncLockRepository
+	keyRepo       registryPorts.SubscriberKeyRepository
+	archive       registryPorts.LookupArchive
+	holdRepo      registryPorts.DeactivationHoldRepository
+	policyApplier NewSellerPolicyApplier
+	registries    map[string]config.RegistryEnvConfig
+	lifecycles    map[string]catalogPorts.Lifecycle
+	registryEnv   string
+	concurrency   int
+	lockTTL       time.Duration
+
+	maxDeactivations       int
+	maxDeactivationPercent float64
+
+	shutdownCtx context.Context
+	shutdown    context.CancelFunc
+
+	jobsMu sync.Mutex
+	jobs   map[string]*syncJob
+	jobsWG sync.WaitGroup
+}
+
+func NewONDCService(sellerRepo catalogPorts.SellerRepository, runRepo registryPorts.SyncRunRepository, lockRepo registryPorts.SyncLockRepository, keyRepo registryPorts.SubscriberKeyRepository, archive registryPorts.LookupArchive, holdRepo registryPorts.DeactivationHoldRepository, policyApplier NewSellerPolicyApplier, cfg *config.Config) *ONDCService {
+	// Retries are driven by lookup so that every attempt is signed afresh
 	client := resty.New()
-	client.SetTimeout(30 * time.Second)
-	client.SetRetryCount(3)
-	client.SetRetryWaitTime(5 * time.Second)
+	client.SetTimeout(cfg.RegistryHTTPTimeout)
+
+	breakers := make(map[string]*circuitBreaker, len(cfg.Registries))
+	for name := range cfg.Registries {
+		breakers[name] = newCircuitBreaker(cfg)
+	}
+
+	concurrency := cfg.RegistrySyncConcurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	lockTTL := cfg.RegistrySyncLockTTL
+	if lockTTL <= 0 {
+		lockTTL = 2 * time.Minute
+	}
+	lifecycles := make(map[string]catalogPorts.Lifecycle, len(cfg.RegistryStatusLifecycle))
+	for status, lifecycle := range cfg.RegistryStatusLifecycle {
+		lifecycles[strings.ToUpper(status)] = catalogPorts.Lifecycle(strings.ToUpper(lifecycle))
+	}
+	shutdownCtx, shutdown := context.WithCancel(context.Background())
 
 	return &ONDCService{
-		client:       client,
-		crypto:       crypto.NewONDCCrypto(),
-		sellerRepo:   sellerRepo,
-		domains:      cfg.Domains,
-		registryURL:  cfg.RegistryURL,
-		privateKey:   cfg.PrivateKey,
-		subscriberID: cfg.SubscriberID,
-		uniqueKeyID:  cfg.UniqueKeyID,
-		registryEnv:  cfg.RegistryEnv,
+		client:        client,
+		crypto:        crypto.NewONDCCrypto(),
+		retry:         newRetryPolicy(cfg),
+		breakers:      breakers,
+		sellerRepo:    sellerRepo,
+		runRepo:       runRepo,
+		lockRepo:      lockRepo,
+		keyRepo:       keyRepo,
+		archive:       archive,
+		holdRepo:      holdRepo,
+		policyApplier: policyApplier,
+		registries:    cfg.Registries,
+		lifecycles:    lifecycles,
+		registryEnv:   cfg.RegistryEnv,
+		concurrency:   concurrency,
+		lockTTL:       lockTTL,
+
+		maxDeactivations:       cfg.RegistryDeactivationMaxCount,
+		maxDeactivationPercent: cfg.RegistryDeactivationMaxPercent,
+
+		shutdownCtx: shutdownCtx,
+		shutdown:    shutdown,
+		jobs:        make(map[string]*syncJob),
 	}
 }
 
-func (s *ONDCService) SyncRegistry(req registryPorts.SyncRegistryRequest) (*registryPorts.SyncRegistryResponse, error) {
-	runAt := time.Now()
-	response := &registryPorts.SyncRegistryResponse{
-		RegistryEnv: req.RegistryEnv,
-		RunAt:       runAt.Format(time.RFC3339),
-		Domains:     []registryPorts.DomainSyncSummary{},
+// runSync reconciles the sellers of every domain of a queued run with the
+// registry. Domains are processed concurrently, bounded by the configured
+// worker limit. Cancelling ctx stops in-flight work; the cause is recorded as
+// the run's error. Every per-domain outcome is recorded in the run history as
+// soon as it is known so the job can be polled while it runs.
+func (s *ONDCService) runSync(ctx context.Context, run *registryPorts.RegistrySyncRun, registryEnv string, domains []string, criteria registryPorts.LookupCriteria) {
+	// Run history must be written even when the sync itself is cancelled
+	recordCtx := context.WithoutCancel(ctx)
+
+	if err := s.runRepo.MarkSyncRunRunning(recordCtx, run.RunID); err != nil {
+		log.Error(ctx, err, "Failed to mark registry sync run as running")
 	}
 
-	for _, domain := range req.Domains {
-		summary := registryPorts.DomainSyncSummary{Domain: domain}
+	domainErrs := make([]error, len(domains))
+	seen := newSeenSubscribers()
 
-		registrySellers, err := s.FetchSellersFromRegistry(domain)
-		if err != nil {
-			log.Error(context.Background(), err, fmt.Sprintf("Failed to fetch sellers from registry for domain %s", domain))
-			continue
-		}
-		summary.TotalSellersInRegistry = len(registrySellers)
+	var wg sync.WaitGroup
+	workers := make(chan struct{}, s.concurrency)
+	for i, domain := range domains {
+		wg.Add(1)
+		go func(i int, domain string) {
+			defer wg.Done()
+
+			select {
+			case workers <- struct{}{}:
+				defer func() { <-workers }()
+			case <-ctx.Done():
+				domainErrs[i] = ctx.Err()
+				return
+			}
+
+			startedAt := time.Now()
+			summary, err := s.syncDomain(ctx, run, registryEnv, domain, criteria, seen)
+			if err != nil {
+				log.Error(ctx, err, fmt.Sprintf("Failed to sync registry for domain %s", domain))
+				domainErrs[i] = err
+			}
+			s.recordDomainRun(recordCtx, run, domain, startedAt, summary, err)
+		}(i, domain)
+	}
+	wg.Wait()
 
-		dbSellers, err := s.sellerRepo.GetSellersByDomainAndRegistry(domain, req.RegistryEnv)
+	failed := 0
+	for _, err := range domainErrs {
 		if err != nil {
-			log.Error(context.Background(), err, fmt.Sprintf("Failed to fetch sellers from DB for domain %s", domain))
-			continue
+			failed++
 		}
+	}
+
+	var runErr error
+	if ctx.Err() != nil {
+		runErr = fmt.Errorf("registry sync cancelled: %w", context.Cause(ctx))
+	}
+	// A key missing from a domain that failed may still be published there
+	if failed == 0 && runErr == nil && run.ReplayOfRunID == nil {
+		s.markRotatedSubscriberKeys(recordCtx, registryEnv, seen, run.StartedAt)
+	}
+	s.finishSyncRun(recordCtx, run, len(domains), failed, runErr)
+}
 
-		registrySellerMap := make(map[string]catalogPorts.Seller)
-		now := time.Now()
-		for _, sub := range registrySellers {
-			validFrom, _ := time.Parse(time.RFC3339, sub.ValidFrom)
-			validUntil, _ := time.Parse(time.RFC3339, sub.ValidUntil)
-			raw, _ := json.Marshal(sub)
+// Close cancels any registry sync job still in progress and waits for the
+// jobs to record their outcome.
+func (s *ONDCService) Close() {
+	s.shutdown()
+	s.jobsWG.Wait()
+}
 
-			seller := catalogPorts.Seller{
-				SellerID: sub.SubscriberID, Domain: sub.Domain, RegistryEnv: req.RegistryEnv,
-				Status: sub.Status, Type: "BPP", SubscriberURL: sub.SubscriberID,
-				Country: sub.Country, City: sub.City, ValidFrom: validFrom, ValidUntil: validUntil,
-				Active: true, LastSeenInReg: now, RegistryRaw: string(raw),
-			}
-			registrySellerMap[seller.SellerID] = seller
+func (s *ONDCService) syncDomain(ctx context.Context, run *registryPorts.RegistrySyncRun, registryEnv, domain string, criteria registryPorts.LookupCriteria, seen *seenSubscribers) (*registryPorts.DomainSyncSummary, error) {
+	summary := &registryPorts.DomainSyncSummary{Domain: domain}
+
+	registrySellers, err := s.fetchDomainSellers(ctx, run, registryEnv, domain, criteria)
+	if err != nil {
+		return nil, err
+	}
+	// A replay reproduces the decisions of an archived run against the
+	// current sellers without writing anything but its own run record
+	replay := run.ReplayOfRunID != nil
+	if !replay {
+		s.recordSubscriberKeys(ctx, registryEnv, registrySellers, seen)
+	}
+
+	dbSellers, err := s.sellerRepo.GetSellersByDomainAndRegistry(ctx, domain, registryEnv)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch sellers from DB: %w", err)
+	}
+
+	registrySellerMap := make(map[string]catalogPorts.Seller)
+	sellerLocationMap := make(map[string][]catalogPorts.SellerLocation)
+	now := time.Now()
+	subscriberEntries := groupSubscriberEntries(registrySellers, domain)
+	summary.TotalSellersInRegistry = len(subscriberEntries)
+	for _, entries := range subscriberEntries {
+		// The seller row is built from the primary entry; every entry is kept
+		// as a seller location
+		s.sortSubscriberEntries(entries, now)
+		sub := entries[0]
+		if len(entries) > 1 {
+			summary.MultiEntrySellers++
+		}
+
+		validFrom, fromErr := parseValidityDate(sub.ValidFrom)
+		validUntil, untilErr := parseValidityDate(sub.ValidUntil)
+		if fromErr != nil || untilErr != nil {
+			log.Warnf(ctx, "Ignoring invalid validity dates of %s in %s: %v", sub.SubscriberID, domain, errors.Join(fromErr, untilErr))
+			summary.InvalidValidityDates++
+		}
+		raw, _ := json.Marshal(sub)
+		subscriberType := sub.Type
+		if subscriberType == "" {
+			subscriberType = criteria.Type
+		}
+		subscriberURL := sub.SubscriberURL
+		if subscriberURL == "" {
+			subscriberURL = sub.SubscriberID
 		}
 
-		dbSellerMap := make(map[string]catalogPorts.Seller)
-		for _, seller := range dbSellers {
-			dbSellerMap[seller.SellerID] = seller
+		seller := catalogPorts.Seller{
+			SellerID: sub.SubscriberID, Domain: domain, RegistryEnv: registryEnv,
+			Status: sub.Status, Type: subscriberType, SubscriberURL: subscriberURL, MSN: sub.MSN,
+			Country: sub.Country, City: sub.City, ValidFrom: validFrom, ValidUntil: validUntil,
+			LastSeenInReg: now, RegistryRaw: string(raw), ContentHash: entriesHash(entries),
 		}
+		seller.Lifecycle = s.lifecycleFor(sub.Status)
+		seller.Active = seller.Lifecycle == catalogPorts.LifecycleActive && seller.WithinValidity(now)
+		switch seller.Lifecycle {
+		case catalogPorts.LifecyclePending:
+			summary.PendingSellers++
+		case catalogPorts.LifecycleInactive:
+			summary.InactiveByStatus++
+		}
+		registrySellerMap[seller.SellerID] = seller
+		sellerLocationMap[seller.SellerID] = sellerLocations(seller, entries)
+	}
 
-		var sellersToInsert []catalogPorts.Seller
-		var sellersToUpdate []catalogPorts.Seller
-		var removedSellerIDs []string
+	dbSellerMap := make(map[string]catalogPorts.Seller)
+	for _, seller := range dbSellers {
+		dbSellerMap[seller.SellerID] = seller
+	}
+
+	var sellersToInsert []catalogPorts.Seller
+	var sellersToUpdate []catalogPorts.Seller
+	var unchangedSellerIDs []string
+	var removedSellerIDs []string
+
+	// A city-scoped lookup only returns a seller's entries in that city. It
+	// leaves alone the rows of sellers listed under another city, and compares
+	// the rest on their tracked fields, keeping the content hash of the last
+	// full sync
+	scopeCity := lookupScopeCity(criteria)
+	for id, seller := range registrySellerMap {
+		dbSeller, exists := dbSellerMap[id]
+		if !exists {
+			sellersToInsert = append(sellersToInsert, seller)
+			continue
+		}
 
-		for id, seller := range registrySellerMap {
-			if _, exists := dbSellerMap[id]; !exists {
-				sellersToInsert = append(sellersToInsert, seller)
-			} else {
-				sellersToUpdate = append(sellersToUpdate, seller)
+		changed := dbSeller.ContentHash != seller.ContentHash
+		if scopeCity != "" {
+			if dbSeller.City != scopeCity {
+				unchangedSellerIDs = append(unchangedSellerIDs, id)
+				continue
 			}
+			seller.ContentHash = dbSeller.ContentHash
+			changed = len(diffSellers(dbSeller, seller)) > 0
 		}
+		switch {
+		case changed || dbSeller.Active != seller.Active || dbSeller.Lifecycle != seller.Lifecycle:
+			// Sellers that return to the registry are reactivated in place
+			seller.DeactivatedAt, seller.ReactivatedAt = dbSeller.DeactivatedAt, dbSeller.ReactivatedAt
+			switch {
+			case seller.Active && !dbSeller.Active:
+				seller.ReactivatedAt = &now
+				summary.ReactivatedSellers++
+			case !seller.Active && dbSeller.Active:
+				seller.DeactivatedAt = &now
+			}
+			sellersToUpdate = append(sellersToUpdate, seller)
+			if dbSeller.Status != seller.Status {
+				if summary.StatusTransitions == nil {
+					summary.StatusTransitions = make(map[string]int)
+				}
+				summary.StatusTransitions[dbSeller.Status+"->"+seller.Status]++
+			}
+		default:
+			unchangedSellerIDs = append(unchangedSellerIDs, id)
+		}
+	}
 
-		for id := range dbSellerMap {
-			if _, exists := registrySellerMap[id]; !exists {
-				removedSellerIDs = append(removedSellerIDs, id)
+	// A targeted lookup only speaks for the sellers it could have returned
+	activeSellers := 0
+	for id, seller := range dbSellerMap {
+		if !seller.Active || !inLookupScope(seller, criteria) {
+			continue
+		}
+		activeSellers++
+		if _, exists := registrySellerMap[id]; !exists {
+			removedSellerIDs = append(removedSellerIDs, id)
+		}
+	}
+
+	// This sync decides afresh which sellers are gone, so earlier holds are stale
+	if !replay {
+		if err := s.holdRepo.SupersedeDeactivationHolds(ctx, registryEnv, domain); err != nil {
+			log.Error(ctx, err, "Failed to supersede held deactivations")
+		}
+	}
+	// An empty or truncated registry response must not wipe out the domain
+	if reason := s.deactivationThresholdExceeded(len(removedSellerIDs), activeSellers, "missing from the registry"); reason != "" {
+		if !replay {
+			holdID, err := s.holdDeactivations(ctx, run.RunID, registryEnv, domain, removedSellerIDs, activeSellers, reason)
+			if err != nil {
+				log.Error(ctx, err, "Failed to record held deactivations")
 			}
+			summary.DeactivationHoldID = holdID
 		}
+		summary.DeactivationsHeld = true
+		summary.HeldDeactivations = len(removedSellerIDs)
+		removedSellerIDs = nil
+	}
 
-		summary.NewSellers = len(sellersToInsert)
-		summary.UpdatedSellers = len(sellersToUpdate)
-		summary.DeactivatedSellers = len(removedSellerIDs)
+	summary.NewSellers = len(sellersToInsert)
+	summary.UpdatedSellers = len(sellersToUpdate) - summary.ReactivatedSellers
+	summary.UnchangedSellers = len(unchangedSellerIDs)
+	summary.DeactivatedSellers = len(removedSellerIDs)
+	if replay {
+		return summary, ctx.Err()
+	}
 
-		if len(sellersToInsert) > 0 {
-			if err := s.sellerRepo.InsertSellers(sellersToInsert); err != nil {
-				log.Error(context.Background(), err, "Failed to insert new sellers")
+	// The seller rows, their locations and their changelog are written in
+	// one transaction, so a failed write leaves the content hashes unchanged
+	// and the next sync retries every seller
+	writes := catalogPorts.SellerSyncWrites{
+		Domain:       domain,
+		RegistryEnv:  registryEnv,
+		SeenAt:       now,
+		Insert:       sellersToInsert,
+		Update:       sellersToUpdate,
+		Touch:        unchangedSellerIDs,
+		LocationCity: scopeCity,
+		Deactivate:   removedSellerIDs,
+	}
+	changedSellerIDs := make(map[string]bool, len(sellersToInsert)+len(sellersToUpdate))
+	for _, sellers := range [][]catalogPorts.Seller{sellersToInsert, sellersToUpdate} {
+		for _, seller := range sellers {
+			changedSellerIDs[seller.SellerID] = true
+		}
+	}
+	for _, seller := range sellersToInsert {
+		writes.Changes = append(writes.Changes, newSellerChange(run.RunID, seller, catalogPorts.SellerChangeInserted, diffSellers(catalogPorts.Seller{}, seller), now))
+	}
+	for _, seller := range sellersToUpdate {
+		dbSeller := dbSellerMap[seller.SellerID]
+		changeType := catalogPorts.SellerChangeUpdated
+		switch {
+		case seller.Active && !dbSeller.Active:
+			changeType = catalogPorts.SellerChangeReactivated
+		case !seller.Active && dbSeller.Active:
+			changeType = catalogPorts.SellerChangeDeactivated
+		}
+		// Changes to untracked fields still update the row but are not worth a changelog entry
+		if fieldChanges := diffSellers(dbSeller, seller); len(fieldChanges) > 0 {
+			writes.Changes = append(writes.Changes, newSellerChange(run.RunID, seller, changeType, fieldChanges, now))
+		}
+	}
+	deactivated := []catalogPorts.FieldChange{{Field: "active", Old: "true", New: "false"}}
+	for _, id := range removedSellerIDs {
+		writes.Changes = append(writes.Changes, newSellerChange(run.RunID, dbSellerMap[id], catalogPorts.SellerChangeDeactivated, deactivated, now))
+	}
+	// The content hash covers every entry, so only changed sellers need their
+	// locations rewritten. A city-scoped sync cannot tell, and rewrites the
+	// locations of every seller it saw in its city only.
+	for id, locations := range sellerLocationMap {
+		if scopeCity == "" && !changedSellerIDs[id] {
+			continue
+		}
+		writes.LocationSellerIDs = append(writes.LocationSellerIDs, id)
+		if scopeCity == "" {
+			writes.Locations = append(writes.Locations, locations...)
+			continue
+		}
+		dbSeller, exists := dbSellerMap[id]
+		for _, location := range locations {
+			if location.City != scopeCity {
+				continue
 			}
+			// The primary location of a seller listed under another city is there
+			location.Primary = location.Primary && (!exists || dbSeller.City == scopeCity)
+			writes.Locations = append(writes.Locations, location)
 		}
-		if len(sellersToUpdate) > 0 {
-			if err := s.sellerRepo.UpdateSellers(sellersToUpdate); err != nil {
-				log.Error(context.Background(), err, "Failed to update existing sellers")
+	}
+	for _, sellers := range [][]catalogPorts.Seller{sellersToInsert, sellersToUpdate} {
+		for _, seller := range sellers {
+			// Catalog sync is only queued for sellers that are subscribed and valid
+			if seller.Active {
+				writes.CatalogStates = append(writes.CatalogStates, catalogPorts.SellerCatalogState{
+					SellerID: seller.SellerID, Domain: domain, RegistryEnv: registryEnv,
+					Status: catalogPorts.CatalogStatusNotSynced,
+				})
 			}
 		}
+	}
+	// New sellers are marked as pending policy templates along with their
+	// rows, so templates that fail to apply are retried by the next sync
+	// rather than lost once the sellers are no longer new
+	if s.policyApplier != nil {
 		for _, seller := range sellersToInsert {
-			state := &catalogPorts.SellerCatalogState{
-				SellerID: seller.SellerID, Domain: domain, RegistryEnv: req.RegistryEnv,
-				Status: catalogPorts.CatalogStatusNotSynced,
+			writes.PendingPolicyTemplates = append(writes.PendingPolicyTemplates, seller.SellerID)
+		}
+	}
+	if err := s.sellerRepo.ApplySellerSync(ctx, writes); err != nil {
+		return summary, fmt.Errorf("failed to write sellers: %w", err)
+	}
+
+	if s.policyApplier != nil {
+		applied, err := s.applyPendingPolicyTemplates(ctx, registryEnv, domain)
+		summary.TemplatePoliciesApplied = applied
+		if err != nil {
+			return summary, fmt.Errorf("failed to apply policy templates to new sellers: %w", err)
+		}
+	}
+
+	return summary, ctx.Err()
+}
+
+// applyPendingPolicyTemplates applies the policy templates of the sellers of a
+// domain still pending them, both those inserted by this sync and those an
+// earlier sync failed to apply templates to, and clears their markers.
+func (s *ONDCService) applyPendingPolicyTemplates(ctx context.Context, registryEnv, domain string) (int, error) {
+	sellerIDs, err := s.sellerRepo.GetPendingPolicyTemplateSellers(ctx, domain, registryEnv)
+	if err != nil || len(sellerIDs) == 0 {
+		return 0, err
+	}
+	applied, err := s.policyApplier.ApplyPolicyTemplatesToNewSellers(registryEnv, domain, sellerIDs)
+	if err != nil {
+		return applied, err
+	}
+	return applied, s.sellerRepo.ClearPendingPolicyTemplates(ctx, sellerIDs, domain, registryEnv)
+}
+
+// lifecycleFor maps a registry subscriber status to the seller lifecycle.
+// Statuses without a mapping are treated as inactive.
+func (s *ONDCService) lifecycleFor(status string) catalogPorts.Lifecycle {
+	if lifecycle, ok := s.lifecycles[strings.ToUpper(status)]; ok {
+		return lifecycle
+	}
+	return catalogPorts.LifecycleInactive
+}
+
+// parseValidityDate parses a registry validity date, returning nil for a
+// missing date.
+func parseValidityDate(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// contentHash fingerprints a seller's registry record so unchanged sellers can
+// be skipped on the next sync.
+func contentHash(raw []byte) string {
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// uniqueDomains drops repeated domains so the same domain is never reconciled
+// by two workers at once, keeping the first occurrence's position.
+func uniqueDomains(domains []string) []string {
+	seen := make(map[string]bool, len(domains))
+	unique := make([]string, 0, len(domains))
+	for _, domain := range domains {
+		if seen[domain] {
+			continue
+		}
+		seen[domain] = true
+		unique = append(unique, domain)
+	}
+	return unique
+}
+
+// Registry returns the settings of a configured registry env, or
+// ErrUnknownRegistryEnv.
+func (s *ONDCService) Registry(registryEnv string) (config.RegistryEnvConfig, error) {
+	registry, ok := s.registries[registryEnv]
+	if !ok {
+		return config.RegistryEnvConfig{}, fmt.Errorf("%w: %s", ErrUnknownRegistryEnv, registryEnv)
+	}
+	return registry, nil
+}
+
+// Registries returns the settings of every configured registry env.
+func (s *ONDCService) Registries() []config.RegistryEnvConfig {
+	registries := make([]config.RegistryEnvConfig, 0, len(s.registries))
+	for _, registry := range s.registries {
+		registries = append(registries, registry)
+	}
+	sort.Slice(registries, func(i, j int) bool { return registries[i].Name < registries[j].Name })
+	return registries
+}
+
+func (s *ONDCService) FetchSellersFromRegistry(ctx context.Context, registryEnv, domain string, criteria registryPorts.LookupCriteria) (ONDCLookupResponse, error) {
+	body, err := s.fetchRegistryResponse(ctx, registryEnv, domain, criteria)
+	if err != nil {
+		return nil, err
+	}
+	return decodeLookupResponse(body, s.registries[registryEnv].ResponseFormat)
+}
+
+// fetchRegistryResponse returns the raw body of a domain lookup.
+func (s *ONDCService) fetchRegistryResponse(ctx context.Context, registryEnv, domain string, criteria registryPorts.LookupCriteria) ([]byte, error) {
+	registry, err := s.Registry(registryEnv)
+	if err != nil {
+		return nil, err
+	}
+	return s.lookupRaw(ctx, registry, ONDCLookupRequest{
+		Country:      criteria.Country,
+		City:         criteria.City,
+		Type:         criteria.Type,
+		Domain:       domain,
+		SubscriberID: criteria.SubscriberID,
+	})
+}
+
+// resolveLookupCriteria fills the criteria a request left empty with the
+// registry env's defaults.
+func resolveLookupCriteria(registry config.RegistryEnvConfig, requested registryPorts.LookupCriteria) registryPorts.LookupCriteria {
+	criteria := requested
+	if criteria.Country == "" {
+		criteria.Country = registry.Country
+	}
+	if criteria.City == "" {
+		criteria.City = registry.City
+	}
+	if criteria.Type == "" {
+		criteria.Type = registry.Type
+	}
+	return criteria
+}
+
+// lookupScopeCity returns the city a lookup is limited to, or "" when it
+// returns every city.
+func lookupScopeCity(criteria registryPorts.LookupCriteria) string {
+	if criteria.City == "*" {
+		return ""
+	}
+	return criteria.City
+}
+
+// inLookupScope reports whether a stored seller could have been returned by a
+// lookup with the given criteria.
+func inLookupScope(seller catalogPorts.Seller, criteria registryPorts.LookupCriteria) bool {
+	switch {
+	case criteria.SubscriberID != "" && seller.SellerID != criteria.SubscriberID:
+		return false
+	case criteria.Country != "" && seller.Country != criteria.Country:
+		return false
+	case lookupScopeCity(criteria) != "" && seller.City != criteria.City:
+		return false
+	case criteria.Type != "" && seller.Type != criteria.Type:
+		return false
+	}
+	return true
+}
+
+// LookupSubscriber fetches the registry entries of a single subscriber key
+// from a registry env.
+func (s *ONDCService) LookupSubscriber(ctx context.Context, registryEnv, subscriberID, ukID string) (ONDCLookupResponse, error) {
+	registry, err := s.Registry(registryEnv)
+	if err != nil {
+		return nil, err
+	}
+	return s.lookup(ctx, registry, ONDCLookupRequest{SubscriberID: subscriberID, UkID: ukID})
+}
+
+func (s *ONDCService) lookup(ctx context.Context, registry config.RegistryEnvConfig, reqBody ONDCLookupRequest) (ONDCLookupResponse, error) {
+	body, err := s.lookupRaw(ctx, registry, reqBody)
+	if err != nil {
+		return nil, err
+	}
+	return decodeLookupResponse(body, registry.ResponseFormat)
+}
+
+// lookupRaw queries the registry and returns the raw response body, retrying
+// transient failures with backoff. Each attempt is signed afresh so a retry
+// never carries an expired signature. While the registry's circuit breaker is
+// open, lookupRaw fails fast with ErrRegistryUnavailable.
+func (s *ONDCService) lookupRaw(ctx context.Context, registry config.RegistryEnvConfig, reqBody ONDCLookupRequest) ([]byte, error) {
+	breaker := s.breakers[registry.Name]
+
+	var lastErr error
+	for attempt := 1; attempt <= s.retry.maxAttempts; attempt++ {
+		if attempt > 1 {
+			var retryAfter time.Duration
+			var statusErr *RegistryStatusError
+			if errors.As(lastErr, &statusErr) {
+				retryAfter = statusErr.RetryAfter
 			}
-			if err := s.sellerRepo.UpsertCatalogState(state); err != nil {
-				log.Error(context.Background(), err, "Failed to insert catalog state")
+			wait := s.retry.backoff(attempt-1, retryAfter)
+			log.Warnf(ctx, "Registry lookup on %s failed (attempt %d/%d), retrying in %s: %v", registry.Name, attempt-1, s.retry.maxAttempts, wait, lastErr)
+			if err := sleepCtx(ctx, wait); err != nil {
+				return nil, err
 			}
 		}
-		if len(removedSellerIDs) > 0 {
-			if err := s.sellerRepo.DeactivateSellers(removedSellerIDs, domain, req.RegistryEnv); err != nil {
-				log.Error(context.Background(), err, "Failed to deactivate sellers")
+
+		if breaker != nil {
+			if err := breaker.allow(); err != nil {
+				return nil, fmt.Errorf("%w: %s", err, registry.Name)
 			}
 		}
-		response.Domains = append(response.Domains, summary)
+		response, err := s.lookupOnce(ctx, registry, reqBody)
+		if breaker != nil {
+			// Only successes and transient failures say anything about registry
+			// health; a rejected request neither opens nor closes the breaker
+			if ctx.Err() != nil || (err != nil && !isRetryable(err)) {
+				breaker.abandon()
+			} else if state, changed := breaker.record(err != nil); changed {
+				log.Warnf(ctx, "Registry circuit breaker for %s is now %s", registry.Name, state)
+			}
+		}
+		if err == nil {
+			return response, nil
+		}
+		if !isRetryable(err) {
+			return nil, err
+		}
+		lastErr = err
 	}
-	return response, nil
+	return nil, lastErr
 }
 
-func (s *ONDCService) FetchSellersFromRegistry(domain string) (ONDCLookupResponse, error) {
-	reqBody := ONDCLookupRequest{Country: "IND", Type: "BPP", Domain: domain}
-	authHeader, err := s.generateAuthHeader(reqBody)
+func (s *ONDCService) lookupOnce(ctx context.Context, registry config.RegistryEnvConfig, reqBody ONDCLookupRequest) ([]byte, error) {
+	authHeader, err := s.generateAuthHeader(registry, reqBody)
 	if err != nil {
 		return nil, err
 	}
-	var response ONDCLookupResponse
 	resp, err := s.client.R().
+		SetContext(ctx).
 		SetHeader("Content-Type", "application/json").
 		SetHeader("Authorization", authHeader).
 		SetBody(reqBody).
-		SetResult(&response).
-		Post(s.registryURL)
+		Post(registry.URL)
 	if err != nil {
 		return nil, err
 	}
 	if resp.StatusCode() != 200 {
-		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode(), resp.String())
+		return nil, &RegistryStatusError{
+			StatusCode: resp.StatusCode(),
+			Body:       resp.String(),
+			RetryAfter: parseRetryAfter(resp.Header().Get("Retry-After")),
+		}
 	}
-	return response, nil
+	return resp.Body(), nil
 }
 
-func (s *ONDCService) generateAuthHeader(body ONDCLookupRequest) (string, error) {
+func (s *ONDCService) generateAuthHeader(registry config.RegistryEnvConfig, body ONDCLookupRequest) (string, error) {
 	payload, err := json.Marshal(body)
 	if err != nil {
 		return "", err
 	}
-	if s.privateKey == "" {
-		return "", fmt.Errorf("PRIVATE_KEY not configured")
+	if registry.PrivateKey == "" {
+		return "", fmt.Errorf("private key not configured for registry env %s", registry.Name)
 	}
 	currentTime := int(time.Now().Unix())
 	ttl := 30
-	signature, err := s.crypto.SignRequest(s.privateKey, payload, currentTime, ttl)
+	signature, err := s.crypto.SignRequest(registry.PrivateKey, payload, currentTime, ttl)
 	if err != nil {
 		return "", err
 	}
 	authHeader := fmt.Sprintf(
 		`Signature keyId="%s|%s|ed25519",algorithm="ed25519",created="%d",expires="%d",headers="(created) (expires) digest",signature="%s"`,
-		s.subscriberID, s.uniqueKeyID, currentTime, currentTime+ttl, signature,
+		registry.SubscriberID, registry.UniqueKeyID, currentTime, currentTime+ttl, signature,
 	)
 	return authHeader, nil
 }
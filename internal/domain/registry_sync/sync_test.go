@@ -0,0 +1,750 @@
+package domain
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"adapter/internal/config"
+	"adapter/internal/mockregistry"
+	catalogPorts "adapter/internal/ports/catalog_sync"
+	registryPorts "adapter/internal/ports/registry_sync"
+)
+
+// The sync tests run the registry sync end to end against the mock registry,
+// with in-memory repositories standing in for PostgreSQL. scripts/registrysync
+// runs similar scenarios against a real database.
+
+const (
+	testRegistryEnv = "mockregistry"
+
+	// The default subscriber of the adapter, registered in the fixtures. The
+	// tests sign with a throwaway key registered with the mock registry.
+	testSubscriberID = "saleor-preprod.bharatvyapaar.com"
+	testUniqueKeyID  = "4a47f723-69ca-48fb-89e9-4d62c13d51b5"
+
+	fixtureDir = "../../mockregistry/testdata"
+)
+
+var testDomains = []string{"ONDC:RET10", "ONDC:RET11"}
+
+func TestSyncRegistry(t *testing.T) {
+	for _, path := range []string{"/lookup", "/v2.0/lookup"} {
+		t.Run(path, func(t *testing.T) {
+			// A third generation drops kirana-hub, the only other active
+			// seller of ONDC:RET10 besides grocer-one
+			generations := loadFixtures(t, "registry-next.json")
+			var dropped []mockregistry.Entry
+			for _, e := range generations {
+				if e.SubscriberID != "kirana-hub.example.com" {
+					dropped = append(dropped, e)
+				}
+			}
+			h := newSyncHarness(t, path, nil, fixturePath("registry.json"), fixturePath("registry-next.json"), writeFixtures(t, dropped))
+
+			// Inserts
+			first := h.sync(t, registryPorts.LookupCriteria{})
+			for domain, want := range map[string]int{"ONDC:RET10": 4, "ONDC:RET11": 2} {
+				summary := summaryOf(t, first, domain)
+				if summary.NewSellers != want || summary.TotalSellersInRegistry != want {
+					t.Errorf("first run: %s has %d new of %d sellers, want %d", domain, summary.NewSellers, summary.TotalSellersInRegistry, want)
+				}
+			}
+			if seller := h.sellers.get(t, "grocer-one.example.com", "ONDC:RET10"); !seller.Active || seller.Lifecycle != catalogPorts.LifecycleActive {
+				t.Errorf("grocer-one.example.com: active=%v lifecycle=%s, want an active seller", seller.Active, seller.Lifecycle)
+			}
+			if seller := h.sellers.get(t, "freshmart.example.com", "ONDC:RET10"); seller.Active || seller.Lifecycle != catalogPorts.LifecyclePending {
+				t.Errorf("freshmart.example.com: active=%v lifecycle=%s, want an inactive pending seller", seller.Active, seller.Lifecycle)
+			}
+			if seller := h.sellers.get(t, "oldstore.example.com", "ONDC:RET10"); seller.Active {
+				t.Error("oldstore.example.com is active past its validity")
+			}
+			h.sellers.expectLocations(t, "grocer-one.example.com", "ONDC:RET10", "std:011|k-g1", "std:080|k-g1")
+
+			// An unchanged registry changes nothing
+			repeat := h.sync(t, registryPorts.LookupCriteria{})
+			for _, summary := range repeat.Domains {
+				if summary.NewSellers+summary.UpdatedSellers+summary.ReactivatedSellers+summary.DeactivatedSellers != 0 {
+					t.Errorf("repeated run changed sellers of an unchanged registry: %+v", summary)
+				}
+			}
+
+			// Updates
+			h.mock.Advance()
+			next := h.sync(t, registryPorts.LookupCriteria{})
+			summary := summaryOf(t, next, "ONDC:RET10")
+			if summary.NewSellers != 1 || summary.UpdatedSellers+summary.ReactivatedSellers != 3 {
+				t.Errorf("next run: ONDC:RET10 has %d new and %d updated sellers, want 1 and 3", summary.NewSellers, summary.UpdatedSellers+summary.ReactivatedSellers)
+			}
+			if summary.StatusTransitions["INITIATED->SUBSCRIBED"] != 1 {
+				t.Errorf("next run: status transitions %v, want INITIATED->SUBSCRIBED once", summary.StatusTransitions)
+			}
+			if seller := h.sellers.get(t, "freshmart.example.com", "ONDC:RET10"); !seller.Active || seller.Status != "SUBSCRIBED" {
+				t.Errorf("freshmart.example.com: active=%v status=%s, want an active subscribed seller", seller.Active, seller.Status)
+			}
+			h.sellers.get(t, "newcomer.example.com", "ONDC:RET10")
+			h.sellers.expectLocations(t, "grocer-one.example.com", "ONDC:RET10", "std:011|k-g1", "std:033|k-g1", "std:080|k-g1")
+			h.sellers.expectLocations(t, "kirana-hub.example.com", "ONDC:RET10", "std:022|k-kh2")
+
+			// Deactivations
+			h.mock.Advance()
+			last := h.sync(t, registryPorts.LookupCriteria{})
+			if summary := summaryOf(t, last, "ONDC:RET10"); summary.DeactivatedSellers != 1 || summary.DeactivationsHeld {
+				t.Errorf("last run: ONDC:RET10 deactivated %d sellers (held: %v), want 1", summary.DeactivatedSellers, summary.DeactivationsHeld)
+			}
+			if seller := h.sellers.get(t, "kirana-hub.example.com", "ONDC:RET10"); seller.Active || seller.DeactivatedAt == nil {
+				t.Error("kirana-hub.example.com is still active after leaving the registry")
+			}
+			if changes := h.sellers.changesOf("kirana-hub.example.com", catalogPorts.SellerChangeDeactivated); changes != 1 {
+				t.Errorf("kirana-hub.example.com has %d deactivation changes, want 1", changes)
+			}
+		})
+	}
+}
+
+func TestSyncRegistryScopedToCity(t *testing.T) {
+	h := newSyncHarness(t, "/v2.0/lookup", nil, fixturePath("registry.json"), fixturePath("registry-next.json"))
+	h.sync(t, registryPorts.LookupCriteria{})
+	primaryCity := h.sellers.get(t, "grocer-one.example.com", "ONDC:RET10").City
+
+	// grocer-one.example.com adds std:033, outside the scoped city
+	h.mock.Advance()
+	run := h.sync(t, registryPorts.LookupCriteria{City: "std:080"})
+	summary := summaryOf(t, run, "ONDC:RET10")
+	if summary.DeactivatedSellers != 0 {
+		t.Errorf("scoped run deactivated %d sellers outside its city", summary.DeactivatedSellers)
+	}
+	if city := h.sellers.get(t, "grocer-one.example.com", "ONDC:RET10").City; city != primaryCity {
+		t.Errorf("scoped run moved grocer-one.example.com from %s to %s", primaryCity, city)
+	}
+	h.sellers.expectLocations(t, "grocer-one.example.com", "ONDC:RET10", "std:011|k-g1", "std:080|k-g1")
+	h.sellers.expectLocations(t, "kirana-hub.example.com", "ONDC:RET10", "std:022|k-kh1")
+}
+
+func TestSyncRegistryRetriesPolicyTemplates(t *testing.T) {
+	policies := &memoryPolicies{failing: true}
+	h := newSyncHarness(t, "/lookup", policies, fixturePath("registry.json"))
+
+	// The sellers are stored even though their templates failed to apply
+	failed := h.run(t, registryPorts.LookupCriteria{})
+	if failed.Status == string(registryPorts.SyncRunStatusCompleted) {
+		t.Fatalf("run %s completed although policy templates failed to apply", failed.RunID)
+	}
+	h.sellers.get(t, "grocer-one.example.com", "ONDC:RET10")
+
+	// The next sync finds no new sellers but still applies their templates
+	policies.setFailing(false)
+	retried := h.sync(t, registryPorts.LookupCriteria{})
+	for domain, want := range map[string]int{"ONDC:RET10": 4, "ONDC:RET11": 2} {
+		summary := summaryOf(t, retried, domain)
+		if summary.NewSellers != 0 || summary.TemplatePoliciesApplied != want {
+			t.Errorf("retried run: %s has %d new sellers and %d template policies, want 0 and %d", domain, summary.NewSellers, summary.TemplatePoliciesApplied, want)
+		}
+		if pending := h.sellers.pendingPolicyTemplates(domain); len(pending) != 0 {
+			t.Errorf("retried run left %s pending policy templates for %v", domain, pending)
+		}
+	}
+
+	// Templates are applied once
+	again := h.sync(t, registryPorts.LookupCriteria{})
+	if applied := summaryOf(t, again, "ONDC:RET10").TemplatePoliciesApplied; applied != 0 {
+		t.Errorf("repeated run applied %d template policies, want 0", applied)
+	}
+}
+
+func TestExpireSellers(t *testing.T) {
+	h := newSyncHarness(t, "/lookup", nil, fixturePath("registry.json"))
+	h.sync(t, registryPorts.LookupCriteria{})
+	past := time.Now().Add(-time.Hour)
+	h.sellers.update("grocer-one.example.com", "ONDC:RET10", func(seller *catalogPorts.Seller) { seller.ValidUntil = &past })
+	for _, id := range []string{"grocer-one.example.com", "foodbox.example.com"} {
+		h.sellers.update(id, "ONDC:RET11", func(seller *catalogPorts.Seller) { seller.ValidUntil = &past })
+	}
+	ctx := context.Background()
+
+	// A domain being synced is left for the next call
+	if _, err := h.runs.AcquireSyncLocks(ctx, testRegistryEnv, []string{"ONDC:RET10"}, "running-sync", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	h.service.maxDeactivations, h.service.maxDeactivationPercent = 0, 60
+	expired, err := h.service.ExpireSellers(ctx)
+	if err != nil || expired != 0 {
+		t.Fatalf("ExpireSellers() = %d, %v; want 0 sellers while ONDC:RET10 is synced and ONDC:RET11 is held", expired, err)
+	}
+	if seller := h.sellers.get(t, "grocer-one.example.com", "ONDC:RET10"); !seller.Active {
+		t.Error("grocer-one.example.com was expired while its domain was synced")
+	}
+
+	// Expiring both active sellers of ONDC:RET11 exceeds 60%
+	if holds := h.holds.held("ONDC:RET11"); len(holds) != 1 || holds[0].RunID != "" || holds[0].SellerCount != 2 {
+		t.Errorf("ONDC:RET11 has holds %+v, want one expiry hold of two sellers", holds)
+	}
+	if seller := h.sellers.get(t, "foodbox.example.com", "ONDC:RET11"); !seller.Active {
+		t.Error("foodbox.example.com was expired past the deactivation threshold")
+	}
+
+	// Expiring again replaces the hold instead of adding another, while one
+	// of the two active sellers of ONDC:RET10 is within 60%
+	if err := h.runs.ReleaseSyncLocks(ctx, testRegistryEnv, "running-sync"); err != nil {
+		t.Fatal(err)
+	}
+	expired, err = h.service.ExpireSellers(ctx)
+	if err != nil || expired != 1 {
+		t.Fatalf("ExpireSellers() = %d, %v; want 1 seller", expired, err)
+	}
+	if seller := h.sellers.get(t, "grocer-one.example.com", "ONDC:RET10"); seller.Active || seller.DeactivatedAt == nil {
+		t.Error("grocer-one.example.com is still active past its validity")
+	}
+	if changes := h.sellers.changesOf("grocer-one.example.com", catalogPorts.SellerChangeDeactivated); changes != 1 {
+		t.Errorf("grocer-one.example.com has %d deactivation changes, want 1", changes)
+	}
+	if holds := h.holds.held("ONDC:RET11"); len(holds) != 1 {
+		t.Errorf("ONDC:RET11 has %d pending holds, want 1", len(holds))
+	}
+}
+
+type syncHarness struct {
+	mock    *mockregistry.Server
+	service *ONDCService
+	sellers *memorySellers
+	runs    *memoryRuns
+	holds   *memoryHolds
+}
+
+func newSyncHarness(t *testing.T, path string, policies NewSellerPolicyApplier, fixtures ...string) *syncHarness {
+	t.Helper()
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock, err := mockregistry.New(mockregistry.Config{
+		Fixtures:         fixtures,
+		VerifySignatures: true,
+		Keys:             map[string]string{testSubscriberID + "|" + testUniqueKeyID: base64.StdEncoding.EncodeToString(publicKey)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(mock.Handler())
+	t.Cleanup(ts.Close)
+
+	cfg := &config.Config{
+		RegistryEnv: testRegistryEnv,
+		Registries: map[string]config.RegistryEnvConfig{
+			testRegistryEnv: {
+				Name:           testRegistryEnv,
+				URL:            ts.URL + path,
+				SubscriberID:   testSubscriberID,
+				UniqueKeyID:    testUniqueKeyID,
+				PrivateKey:     base64.StdEncoding.EncodeToString(privateKey),
+				Domains:        testDomains,
+				Country:        "IND",
+				Type:           "BPP",
+				ResponseFormat: LookupFormatAuto,
+			},
+		},
+		RegistryStatusLifecycle: map[string]string{
+			"SUBSCRIBED": "active",
+			"INITIATED":  "pending",
+		},
+		RegistrySyncConcurrency:         2,
+		RegistrySyncLockTTL:             time.Minute,
+		RegistryHTTPTimeout:             5 * time.Second,
+		RegistryRetryMaxAttempts:        2,
+		RegistryRetryBaseDelay:          10 * time.Millisecond,
+		RegistryRetryMaxDelay:           100 * time.Millisecond,
+		RegistryBreakerFailureThreshold: 10,
+		RegistryBreakerOpenTimeout:      time.Second,
+		RegistryDeactivationMaxCount:    100,
+	}
+
+	sellers := newMemorySellers()
+	runs := newMemoryRuns()
+	holds := &memoryHolds{}
+	service := NewONDCService(sellers, runs, runs, memoryKeys{}, nil, holds, policies, cfg)
+	t.Cleanup(service.Close)
+	return &syncHarness{mock: mock, service: service, sellers: sellers, runs: runs, holds: holds}
+}
+
+// sync runs a sync job of every domain, waits for it and expects it to
+// complete.
+func (h *syncHarness) sync(t *testing.T, criteria registryPorts.LookupCriteria) *registryPorts.SyncRunResponse {
+	t.Helper()
+	run := h.run(t, criteria)
+	if run.Status != string(registryPorts.SyncRunStatusCompleted) {
+		t.Fatalf("run %s finished %s, want %s (errors: %v)", run.RunID, run.Status, registryPorts.SyncRunStatusCompleted, run.Errors)
+	}
+	return run
+}
+
+// run runs a sync job of every domain and waits for it to finish.
+func (h *syncHarness) run(t *testing.T, criteria registryPorts.LookupCriteria) *registryPorts.SyncRunResponse {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	job, err := h.service.StartSyncJob(ctx, registryPorts.SyncRegistryRequest{
+		RegistryEnv:    testRegistryEnv,
+		Domains:        testDomains,
+		TriggerSource:  registryPorts.TriggerSourceAPI,
+		LookupCriteria: criteria,
+	})
+	if err != nil {
+		t.Fatalf("failed to start sync: %v", err)
+	}
+	run, err := h.service.WaitSyncJob(ctx, job.JobID)
+	if err != nil {
+		t.Fatalf("failed to wait for sync: %v", err)
+	}
+	return run
+}
+
+func summaryOf(t *testing.T, run *registryPorts.SyncRunResponse, domain string) registryPorts.DomainSyncSummary {
+	t.Helper()
+	for _, summary := range run.Domains {
+		if summary.Domain == domain {
+			return summary
+		}
+	}
+	t.Fatalf("run %s has no summary for %s (errors: %v)", run.RunID, domain, run.Errors)
+	return registryPorts.DomainSyncSummary{}
+}
+
+func fixturePath(name string) string {
+	return filepath.Join(fixtureDir, name)
+}
+
+func loadFixtures(t *testing.T, name string) []mockregistry.Entry {
+	t.Helper()
+	entries, err := mockregistry.LoadFixtures(fixturePath(name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entries
+}
+
+func writeFixtures(t *testing.T, entries []mockregistry.Entry) string {
+	t.Helper()
+	raw, err := json.Marshal(entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "registry.json")
+	if err := os.WriteFile(path, raw, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// memorySellers keeps sellers in memory. Methods the sync does not call are
+// left to the embedded nil interface.
+type memorySellers struct {
+	catalogPorts.SellerRepository
+
+	mu        sync.Mutex
+	sellers   map[string]catalogPorts.Seller
+	locations map[string][]catalogPorts.SellerLocation
+	changes   []catalogPorts.SellerChange
+	pending   map[string]bool
+}
+
+func newMemorySellers() *memorySellers {
+	return &memorySellers{
+		sellers:   make(map[string]catalogPorts.Seller),
+		locations: make(map[string][]catalogPorts.SellerLocation),
+		pending:   make(map[string]bool),
+	}
+}
+
+func sellerKey(sellerID, domain, registryEnv string) string {
+	return registryEnv + "|" + domain + "|" + sellerID
+}
+
+func (m *memorySellers) GetSellersByDomainAndRegistry(ctx context.Context, domain, registryEnv string) ([]catalogPorts.Seller, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var sellers []catalogPorts.Seller
+	for _, seller := range m.sellers {
+		if seller.Domain == domain && seller.RegistryEnv == registryEnv {
+			sellers = append(sellers, seller)
+		}
+	}
+	return sellers, nil
+}
+
+func (m *memorySellers) ApplySellerSync(ctx context.Context, writes catalogPorts.SellerSyncWrites) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, sellers := range [][]catalogPorts.Seller{writes.Insert, writes.Update} {
+		for _, seller := range sellers {
+			m.sellers[sellerKey(seller.SellerID, seller.Domain, seller.RegistryEnv)] = seller
+		}
+	}
+	for _, id := range writes.Touch {
+		key := sellerKey(id, writes.Domain, writes.RegistryEnv)
+		seller := m.sellers[key]
+		seller.LastSeenInReg = writes.SeenAt
+		m.sellers[key] = seller
+	}
+	for _, id := range writes.LocationSellerIDs {
+		key := sellerKey(id, writes.Domain, writes.RegistryEnv)
+		var kept []catalogPorts.SellerLocation
+		for _, location := range m.locations[key] {
+			if writes.LocationCity != "" && location.City != writes.LocationCity {
+				kept = append(kept, location)
+			}
+		}
+		m.locations[key] = kept
+	}
+	for _, location := range writes.Locations {
+		key := sellerKey(location.SellerID, location.Domain, location.RegistryEnv)
+		m.locations[key] = append(m.locations[key], location)
+	}
+	now := time.Now()
+	for _, id := range writes.Deactivate {
+		key := sellerKey(id, writes.Domain, writes.RegistryEnv)
+		seller := m.sellers[key]
+		seller.Active = false
+		seller.DeactivatedAt = &now
+		m.sellers[key] = seller
+	}
+	m.changes = append(m.changes, writes.Changes...)
+	for _, id := range writes.PendingPolicyTemplates {
+		m.pending[sellerKey(id, writes.Domain, writes.RegistryEnv)] = true
+	}
+	return nil
+}
+
+func (m *memorySellers) GetPendingPolicyTemplateSellers(ctx context.Context, domain, registryEnv string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var sellerIDs []string
+	for key := range m.pending {
+		seller := m.sellers[key]
+		if seller.Domain == domain && seller.RegistryEnv == registryEnv {
+			sellerIDs = append(sellerIDs, seller.SellerID)
+		}
+	}
+	sort.Strings(sellerIDs)
+	return sellerIDs, nil
+}
+
+func (m *memorySellers) ClearPendingPolicyTemplates(ctx context.Context, sellerIDs []string, domain, registryEnv string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, id := range sellerIDs {
+		delete(m.pending, sellerKey(id, domain, registryEnv))
+	}
+	return nil
+}
+
+func (m *memorySellers) pendingPolicyTemplates(domain string) []string {
+	sellerIDs, _ := m.GetPendingPolicyTemplateSellers(context.Background(), domain, testRegistryEnv)
+	return sellerIDs
+}
+
+func (m *memorySellers) GetExpiredSellers(ctx context.Context, now time.Time) ([]catalogPorts.Seller, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var sellers []catalogPorts.Seller
+	for _, seller := range m.sellers {
+		if seller.Active && seller.ValidUntil != nil && !seller.ValidUntil.After(now) {
+			sellers = append(sellers, seller)
+		}
+	}
+	sort.Slice(sellers, func(i, j int) bool {
+		return sellerKey(sellers[i].SellerID, sellers[i].Domain, sellers[i].RegistryEnv) < sellerKey(sellers[j].SellerID, sellers[j].Domain, sellers[j].RegistryEnv)
+	})
+	return sellers, nil
+}
+
+func (m *memorySellers) DeactivateExpiredSellers(ctx context.Context, sellerIDs []string, domain, registryEnv string, now time.Time) ([]catalogPorts.Seller, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var deactivated []catalogPorts.Seller
+	for _, id := range sellerIDs {
+		key := sellerKey(id, domain, registryEnv)
+		seller, ok := m.sellers[key]
+		if !ok || !seller.Active || seller.ValidUntil == nil || seller.ValidUntil.After(now) {
+			continue
+		}
+		seller.Active = false
+		seller.DeactivatedAt = &now
+		m.sellers[key] = seller
+		deactivated = append(deactivated, seller)
+	}
+	return deactivated, nil
+}
+
+func (m *memorySellers) CountActiveSellers(ctx context.Context, domain, registryEnv string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	count := 0
+	for _, seller := range m.sellers {
+		if seller.Active && seller.Domain == domain && seller.RegistryEnv == registryEnv {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *memorySellers) InsertSellerChanges(ctx context.Context, changes []catalogPorts.SellerChange) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.changes = append(m.changes, changes...)
+	return nil
+}
+
+// update changes a stored seller in place.
+func (m *memorySellers) update(sellerID, domain string, change func(*catalogPorts.Seller)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := sellerKey(sellerID, domain, testRegistryEnv)
+	seller := m.sellers[key]
+	change(&seller)
+	m.sellers[key] = seller
+}
+
+func (m *memorySellers) get(t *testing.T, sellerID, domain string) catalogPorts.Seller {
+	t.Helper()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	seller, ok := m.sellers[sellerKey(sellerID, domain, testRegistryEnv)]
+	if !ok {
+		t.Fatalf("seller %s was not stored in %s", sellerID, domain)
+	}
+	return seller
+}
+
+// expectLocations checks a seller's locations, given as "city|ukId".
+func (m *memorySellers) expectLocations(t *testing.T, sellerID, domain string, want ...string) {
+	t.Helper()
+	m.mu.Lock()
+	var got []string
+	for _, location := range m.locations[sellerKey(sellerID, domain, testRegistryEnv)] {
+		got = append(got, location.City+"|"+location.UkID)
+	}
+	m.mu.Unlock()
+	sort.Strings(got)
+
+	if len(got) != len(want) {
+		t.Errorf("%s has locations %v in %s, want %v", sellerID, got, domain, want)
+		return
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Errorf("%s has locations %v in %s, want %v", sellerID, got, domain, want)
+			return
+		}
+	}
+}
+
+func (m *memorySellers) changesOf(sellerID string, changeType catalogPorts.SellerChangeType) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	count := 0
+	for _, change := range m.changes {
+		if change.SellerID == sellerID && change.ChangeType == changeType {
+			count++
+		}
+	}
+	return count
+}
+
+// memoryRuns keeps sync runs and their domain leases in memory.
+type memoryRuns struct {
+	registryPorts.SyncRunRepository
+
+	mu    sync.Mutex
+	runs  map[string]*registryPorts.RegistrySyncRun
+	locks map[string]string
+}
+
+func newMemoryRuns() *memoryRuns {
+	return &memoryRuns{
+		runs:  make(map[string]*registryPorts.RegistrySyncRun),
+		locks: make(map[string]string),
+	}
+}
+
+func (m *memoryRuns) CreateSyncRun(ctx context.Context, run *registryPorts.RegistrySyncRun) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored := *run
+	m.runs[run.RunID] = &stored
+	return nil
+}
+
+func (m *memoryRuns) MarkSyncRunRunning(ctx context.Context, runID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.runs[runID].Status = registryPorts.SyncRunStatusRunning
+	return nil
+}
+
+func (m *memoryRuns) SaveSyncRunDomain(ctx context.Context, runDomain *registryPorts.RegistrySyncRunDomain) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	run := m.runs[runDomain.RunID]
+	run.Domains = append(run.Domains, *runDomain)
+	return nil
+}
+
+func (m *memoryRuns) IncrementSyncRunProgress(ctx context.Context, runID string, sellersProcessed int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.runs[runID].DomainsDone++
+	m.runs[runID].SellersProcessed += sellersProcessed
+	return nil
+}
+
+func (m *memoryRuns) IsSyncRunCancelRequested(ctx context.Context, runID string) (bool, error) {
+	return false, nil
+}
+
+func (m *memoryRuns) FinishSyncRun(ctx context.Context, runID string, status registryPorts.SyncRunStatus, finishedAt time.Time, runErr *string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	run := m.runs[runID]
+	run.Status, run.FinishedAt, run.Error = status, &finishedAt, runErr
+	return nil
+}
+
+func (m *memoryRuns) GetSyncRun(runID string) (*registryPorts.RegistrySyncRun, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	run := *m.runs[runID]
+	run.Domains = append([]registryPorts.RegistrySyncRunDomain(nil), run.Domains...)
+	return &run, nil
+}
+
+func (m *memoryRuns) AcquireSyncLocks(ctx context.Context, registryEnv string, domains []string, holder string, ttl time.Duration) ([]registryPorts.RegistrySyncLock, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var conflicts []registryPorts.RegistrySyncLock
+	for _, domain := range domains {
+		if current, ok := m.locks[registryEnv+"|"+domain]; ok && current != holder {
+			conflicts = append(conflicts, registryPorts.RegistrySyncLock{RegistryEnv: registryEnv, Domain: domain, Holder: current})
+		}
+	}
+	if len(conflicts) > 0 {
+		return conflicts, nil
+	}
+	for _, domain := range domains {
+		m.locks[registryEnv+"|"+domain] = holder
+	}
+	return nil, nil
+}
+
+func (m *memoryRuns) RenewSyncLocks(ctx context.Context, registryEnv string, holder string, ttl time.Duration) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var renewed int64
+	for _, current := range m.locks {
+		if current == holder {
+			renewed++
+		}
+	}
+	return renewed, nil
+}
+
+func (m *memoryRuns) ReleaseSyncLocks(ctx context.Context, registryEnv string, holder string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key, current := range m.locks {
+		if current == holder {
+			delete(m.locks, key)
+		}
+	}
+	return nil
+}
+
+// memoryPolicies applies one policy per seller, or fails while failing is set.
+type memoryPolicies struct {
+	mu      sync.Mutex
+	failing bool
+}
+
+func (p *memoryPolicies) setFailing(failing bool) {
+	p.mu.Lock()
+	p.failing = failing
+	p.mu.Unlock()
+}
+
+func (p *memoryPolicies) ApplyPolicyTemplatesToNewSellers(registryEnv, domain string, sellerIDs []string) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.failing {
+		return 0, errors.New("policy templates unavailable")
+	}
+	return len(sellerIDs), nil
+}
+
+// memoryKeys discards what the sync records about keys.
+type memoryKeys struct {
+	registryPorts.SubscriberKeyRepository
+}
+
+func (memoryKeys) UpsertSubscriberKeys(ctx context.Context, keys []registryPorts.SubscriberKey) error {
+	return nil
+}
+
+func (memoryKeys) MarkSubscriberKeysRotated(ctx context.Context, registryEnv string, subscriberIDs []string, seenSince time.Time) error {
+	return nil
+}
+
+// memoryHolds keeps held deactivations in memory.
+type memoryHolds struct {
+	registryPorts.DeactivationHoldRepository
+
+	mu    sync.Mutex
+	holds []registryPorts.HeldDeactivation
+}
+
+func (m *memoryHolds) SupersedeDeactivationHolds(ctx context.Context, registryEnv, domain string) error {
+	m.supersede(registryEnv, domain, func(registryPorts.HeldDeactivation) bool { return true })
+	return nil
+}
+
+func (m *memoryHolds) SupersedeExpiryDeactivationHolds(ctx context.Context, registryEnv, domain string) error {
+	m.supersede(registryEnv, domain, func(hold registryPorts.HeldDeactivation) bool { return hold.RunID == "" })
+	return nil
+}
+
+func (m *memoryHolds) supersede(registryEnv, domain string, match func(registryPorts.HeldDeactivation) bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, hold := range m.holds {
+		if hold.RegistryEnv == registryEnv && hold.Domain == domain && hold.Status == registryPorts.DeactivationHoldStatusHeld && match(hold) {
+			m.holds[i].Status = registryPorts.DeactivationHoldStatusSuperseded
+		}
+	}
+}
+
+func (m *memoryHolds) CreateDeactivationHold(ctx context.Context, hold *registryPorts.HeldDeactivation) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.holds = append(m.holds, *hold)
+	return nil
+}
+
+func (m *memoryHolds) held(domain string) []registryPorts.HeldDeactivation {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var holds []registryPorts.HeldDeactivation
+	for _, hold := range m.holds {
+		if hold.Domain == domain && hold.Status == registryPorts.DeactivationHoldStatusHeld {
+			holds = append(holds, hold)
+		}
+	}
+	return holds
+}
@@ -0,0 +1,95 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	catalogPorts "adapter/internal/ports/catalog_sync"
+	"adapter/internal/shared/log"
+)
+
+// ExpireSellers deactivates every seller whose registry validity has ended and
+// records the deactivations in the seller changelog. Each domain is expired
+// under its sync lease; domains being synced are skipped until the next call.
+// Expiring more of a domain's active sellers than the deactivation threshold
+// allows is held for approval like a sync's deactivations. It returns the
+// number of sellers deactivated.
+func (s *ONDCService) ExpireSellers(ctx context.Context) (int, error) {
+	now := time.Now()
+	expired, err := s.sellerRepo.GetExpiredSellers(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+
+	type domainKey struct{ registryEnv, domain string }
+	var order []domainKey
+	sellerIDs := make(map[domainKey][]string)
+	for _, seller := range expired {
+		key := domainKey{seller.RegistryEnv, seller.Domain}
+		if _, ok := sellerIDs[key]; !ok {
+			order = append(order, key)
+		}
+		sellerIDs[key] = append(sellerIDs[key], seller.SellerID)
+	}
+
+	total := 0
+	var errs []error
+	for _, key := range order {
+		deactivated, err := s.expireDomainSellers(ctx, key.registryEnv, key.domain, sellerIDs[key], now)
+		total += deactivated
+		var alreadyRunning *SyncAlreadyRunningError
+		if errors.As(err, &alreadyRunning) {
+			log.Infof(ctx, "Skipping expired sellers of %s/%s: %v", key.registryEnv, key.domain, err)
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s/%s: %w", key.registryEnv, key.domain, err))
+		}
+	}
+	return total, errors.Join(errs...)
+}
+
+// expireDomainSellers deactivates the expired sellers of one domain while
+// holding its sync lease, or holds them for approval.
+func (s *ONDCService) expireDomainSellers(ctx context.Context, registryEnv, domain string, sellerIDs []string, now time.Time) (int, error) {
+	lockHolder := "expiry:" + uuid.New().String()
+	if err := s.acquireSyncLocks(ctx, registryEnv, []string{domain}, lockHolder); err != nil {
+		return 0, err
+	}
+	defer s.releaseSyncLocks(context.WithoutCancel(ctx), registryEnv, lockHolder)
+
+	active, err := s.sellerRepo.CountActiveSellers(ctx, domain, registryEnv)
+	if err != nil {
+		return 0, err
+	}
+	if reason := s.deactivationThresholdExceeded(len(sellerIDs), active, "past their registry validity"); reason != "" {
+		// Only the latest hold of the hourly expiry is worth approving
+		if err := s.holdRepo.SupersedeExpiryDeactivationHolds(ctx, registryEnv, domain); err != nil {
+			return 0, err
+		}
+		_, err := s.holdDeactivations(ctx, "", registryEnv, domain, sellerIDs, active, reason)
+		return 0, err
+	}
+
+	expired, err := s.sellerRepo.DeactivateExpiredSellers(ctx, sellerIDs, domain, registryEnv, now)
+	if err != nil {
+		return 0, err
+	}
+	if len(expired) == 0 {
+		return 0, nil
+	}
+
+	deactivated := []catalogPorts.FieldChange{{Field: "active", Old: "true", New: "false"}}
+	changes := make([]catalogPorts.SellerChange, 0, len(expired))
+	for _, seller := range expired {
+		changes = append(changes, newSellerChange("", seller, catalogPorts.SellerChangeDeactivated, deactivated, now))
+	}
+	if err := s.sellerRepo.InsertSellerChanges(ctx, changes); err != nil {
+		log.Error(ctx, err, "Failed to record expired seller changes")
+	}
+	return len(expired), nil
+}
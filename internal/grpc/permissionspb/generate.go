@@ -0,0 +1,4 @@
+// Package permissionspb holds the generated gRPC bindings for proto/permissions/v1.
+package permissionspb
+
+//go:generate protoc -I ../../../proto --go_out=. --go_opt=module=adapter/internal/grpc/permissionspb --go-grpc_out=. --go-grpc_opt=module=adapter/internal/grpc/permissionspb permissions/v1/permissions.proto
@@ -0,0 +1,481 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.7
+// 	protoc        v5.28.3
+// source: permissions/v1/permissions.proto
+
+package permissionspb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type QueryPermissionsRequest struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	BapId           string                 `protobuf:"bytes,1,opt,name=bap_id,json=bapId,proto3" json:"bap_id,omitempty"`
+	Domain          string                 `protobuf:"bytes,2,opt,name=domain,proto3" json:"domain,omitempty"`
+	RegistryEnv     string                 `protobuf:"bytes,3,opt,name=registry_env,json=registryEnv,proto3" json:"registry_env,omitempty"`
+	SellerIds       []string               `protobuf:"bytes,4,rep,name=seller_ids,json=sellerIds,proto3" json:"seller_ids,omitempty"`
+	IncludeNoPolicy bool                   `protobuf:"varint,5,opt,name=include_no_policy,json=includeNoPolicy,proto3" json:"include_no_policy,omitempty"`
+	// Echoed back on the response so streaming callers can match replies.
+	CorrelationId string `protobuf:"bytes,6,opt,name=correlation_id,json=correlationId,proto3" json:"correlation_id,omitempty"`
+	// Optional providers within the sellers; provider-level decisions override
+	// the seller-level one.
+	ProviderIds   []string `protobuf:"bytes,7,rep,name=provider_ids,json=providerIds,proto3" json:"provider_ids,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QueryPermissionsRequest) Reset() {
+	*x = QueryPermissionsRequest{}
+	mi := &file_permissions_v1_permissions_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QueryPermissionsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueryPermissionsRequest) ProtoMessage() {}
+
+func (x *QueryPermissionsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_permissions_v1_permissions_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QueryPermissionsRequest.ProtoReflect.Descriptor instead.
+func (*QueryPermissionsRequest) Descriptor() ([]byte, []int) {
+	return file_permissions_v1_permissions_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *QueryPermissionsRequest) GetBapId() string {
+	if x != nil {
+		return x.BapId
+	}
+	return ""
+}
+
+func (x *QueryPermissionsRequest) GetDomain() string {
+	if x != nil {
+		return x.Domain
+	}
+	return ""
+}
+
+func (x *QueryPermissionsRequest) GetRegistryEnv() string {
+	if x != nil {
+		return x.RegistryEnv
+	}
+	return ""
+}
+
+func (x *QueryPermissionsRequest) GetSellerIds() []string {
+	if x != nil {
+		return x.SellerIds
+	}
+	return nil
+}
+
+func (x *QueryPermissionsRequest) GetIncludeNoPolicy() bool {
+	if x != nil {
+		return x.IncludeNoPolicy
+	}
+	return false
+}
+
+func (x *QueryPermissionsRequest) GetCorrelationId() string {
+	if x != nil {
+		return x.CorrelationId
+	}
+	return ""
+}
+
+func (x *QueryPermissionsRequest) GetProviderIds() []string {
+	if x != nil {
+		return x.ProviderIds
+	}
+	return nil
+}
+
+type PermissionDetail struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	SellerId       string                 `protobuf:"bytes,1,opt,name=seller_id,json=sellerId,proto3" json:"seller_id,omitempty"`
+	Domain         string                 `protobuf:"bytes,2,opt,name=domain,proto3" json:"domain,omitempty"`
+	RegistryEnv    string                 `protobuf:"bytes,3,opt,name=registry_env,json=registryEnv,proto3" json:"registry_env,omitempty"`
+	BapId          string                 `protobuf:"bytes,4,opt,name=bap_id,json=bapId,proto3" json:"bap_id,omitempty"`
+	Decision       string                 `protobuf:"bytes,5,opt,name=decision,proto3" json:"decision,omitempty"`
+	DecisionSource *string                `protobuf:"bytes,6,opt,name=decision_source,json=decisionSource,proto3,oneof" json:"decision_source,omitempty"`
+	DecidedAt      *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=decided_at,json=decidedAt,proto3" json:"decided_at,omitempty"`
+	ExpiresAt      *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	ProviderId     string                 `protobuf:"bytes,9,opt,name=provider_id,json=providerId,proto3" json:"provider_id,omitempty"`
+	// SELLER or PROVIDER; empty when there is no policy.
+	PolicyLevel   string `protobuf:"bytes,10,opt,name=policy_level,json=policyLevel,proto3" json:"policy_level,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PermissionDetail) Reset() {
+	*x = PermissionDetail{}
+	mi := &file_permissions_v1_permissions_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PermissionDetail) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PermissionDetail) ProtoMessage() {}
+
+func (x *PermissionDetail) ProtoReflect() protoreflect.Message {
+	mi := &file_permissions_v1_permissions_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PermissionDetail.ProtoReflect.Descriptor instead.
+func (*PermissionDetail) Descriptor() ([]byte, []int) {
+	return file_permissions_v1_permissions_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *PermissionDetail) GetSellerId() string {
+	if x != nil {
+		return x.SellerId
+	}
+	return ""
+}
+
+func (x *PermissionDetail) GetDomain() string {
+	if x != nil {
+		return x.Domain
+	}
+	return ""
+}
+
+func (x *PermissionDetail) GetRegistryEnv() string {
+	if x != nil {
+		return x.RegistryEnv
+	}
+	return ""
+}
+
+func (x *PermissionDetail) GetBapId() string {
+	if x != nil {
+		return x.BapId
+	}
+	return ""
+}
+
+func (x *PermissionDetail) GetDecision() string {
+	if x != nil {
+		return x.Decision
+	}
+	return ""
+}
+
+func (x *PermissionDetail) GetDecisionSource() string {
+	if x != nil && x.DecisionSource != nil {
+		return *x.DecisionSource
+	}
+	return ""
+}
+
+func (x *PermissionDetail) GetDecidedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.DecidedAt
+	}
+	return nil
+}
+
+func (x *PermissionDetail) GetExpiresAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return nil
+}
+
+func (x *PermissionDetail) GetProviderId() string {
+	if x != nil {
+		return x.ProviderId
+	}
+	return ""
+}
+
+func (x *PermissionDetail) GetPolicyLevel() string {
+	if x != nil {
+		return x.PolicyLevel
+	}
+	return ""
+}
+
+type QueryPermissionsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	BapStatus     string                 `protobuf:"bytes,1,opt,name=bap_status,json=bapStatus,proto3" json:"bap_status,omitempty"`
+	Domain        string                 `protobuf:"bytes,2,opt,name=domain,proto3" json:"domain,omitempty"`
+	RegistryEnv   string                 `protobuf:"bytes,3,opt,name=registry_env,json=registryEnv,proto3" json:"registry_env,omitempty"`
+	Permissions   []*PermissionDetail    `protobuf:"bytes,4,rep,name=permissions,proto3" json:"permissions,omitempty"`
+	CorrelationId string                 `protobuf:"bytes,5,opt,name=correlation_id,json=correlationId,proto3" json:"correlation_id,omitempty"`
+	// Set on a streamed response when its request failed; the other fields
+	// are then empty apart from correlation_id.
+	Error         *QueryError `protobuf:"bytes,6,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QueryPermissionsResponse) Reset() {
+	*x = QueryPermissionsResponse{}
+	mi := &file_permissions_v1_permissions_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QueryPermissionsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueryPermissionsResponse) ProtoMessage() {}
+
+func (x *QueryPermissionsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_permissions_v1_permissions_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QueryPermissionsResponse.ProtoReflect.Descriptor instead.
+func (*QueryPermissionsResponse) Descriptor() ([]byte, []int) {
+	return file_permissions_v1_permissions_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *QueryPermissionsResponse) GetBapStatus() string {
+	if x != nil {
+		return x.BapStatus
+	}
+	return ""
+}
+
+func (x *QueryPermissionsResponse) GetDomain() string {
+	if x != nil {
+		return x.Domain
+	}
+	return ""
+}
+
+func (x *QueryPermissionsResponse) GetRegistryEnv() string {
+	if x != nil {
+		return x.RegistryEnv
+	}
+	return ""
+}
+
+func (x *QueryPermissionsResponse) GetPermissions() []*PermissionDetail {
+	if x != nil {
+		return x.Permissions
+	}
+	return nil
+}
+
+func (x *QueryPermissionsResponse) GetCorrelationId() string {
+	if x != nil {
+		return x.CorrelationId
+	}
+	return ""
+}
+
+func (x *QueryPermissionsResponse) GetError() *QueryError {
+	if x != nil {
+		return x.Error
+	}
+	return nil
+}
+
+type QueryError struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Canonical gRPC status code, e.g. 3 for INVALID_ARGUMENT.
+	Code          int32  `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty"`
+	Message       string `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QueryError) Reset() {
+	*x = QueryError{}
+	mi := &file_permissions_v1_permissions_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QueryError) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueryError) ProtoMessage() {}
+
+func (x *QueryError) ProtoReflect() protoreflect.Message {
+	mi := &file_permissions_v1_permissions_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QueryError.ProtoReflect.Descriptor instead.
+func (*QueryError) Descriptor() ([]byte, []int) {
+	return file_permissions_v1_permissions_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *QueryError) GetCode() int32 {
+	if x != nil {
+		return x.Code
+	}
+	return 0
+}
+
+func (x *QueryError) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+var File_permissions_v1_permissions_proto protoreflect.FileDescriptor
+
+const file_permissions_v1_permissions_proto_rawDesc = "" +
+	"\n" +
+	" permissions/v1/permissions.proto\x12\x0epermissions.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\x80\x02\n" +
+	"\x17QueryPermissionsRequest\x12\x15\n" +
+	"\x06bap_id\x18\x01 \x01(\tR\x05bapId\x12\x16\n" +
+	"\x06domain\x18\x02 \x01(\tR\x06domain\x12!\n" +
+	"\fregistry_env\x18\x03 \x01(\tR\vregistryEnv\x12\x1d\n" +
+	"\n" +
+	"seller_ids\x18\x04 \x03(\tR\tsellerIds\x12*\n" +
+	"\x11include_no_policy\x18\x05 \x01(\bR\x0fincludeNoPolicy\x12%\n" +
+	"\x0ecorrelation_id\x18\x06 \x01(\tR\rcorrelationId\x12!\n" +
+	"\fprovider_ids\x18\a \x03(\tR\vproviderIds\"\x99\x03\n" +
+	"\x10PermissionDetail\x12\x1b\n" +
+	"\tseller_id\x18\x01 \x01(\tR\bsellerId\x12\x16\n" +
+	"\x06domain\x18\x02 \x01(\tR\x06domain\x12!\n" +
+	"\fregistry_env\x18\x03 \x01(\tR\vregistryEnv\x12\x15\n" +
+	"\x06bap_id\x18\x04 \x01(\tR\x05bapId\x12\x1a\n" +
+	"\bdecision\x18\x05 \x01(\tR\bdecision\x12,\n" +
+	"\x0fdecision_source\x18\x06 \x01(\tH\x00R\x0edecisionSource\x88\x01\x01\x129\n" +
+	"\n" +
+	"decided_at\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\tdecidedAt\x129\n" +
+	"\n" +
+	"expires_at\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\texpiresAt\x12\x1f\n" +
+	"\vprovider_id\x18\t \x01(\tR\n" +
+	"providerId\x12!\n" +
+	"\fpolicy_level\x18\n" +
+	" \x01(\tR\vpolicyLevelB\x12\n" +
+	"\x10_decision_source\"\x91\x02\n" +
+	"\x18QueryPermissionsResponse\x12\x1d\n" +
+	"\n" +
+	"bap_status\x18\x01 \x01(\tR\tbapStatus\x12\x16\n" +
+	"\x06domain\x18\x02 \x01(\tR\x06domain\x12!\n" +
+	"\fregistry_env\x18\x03 \x01(\tR\vregistryEnv\x12B\n" +
+	"\vpermissions\x18\x04 \x03(\v2 .permissions.v1.PermissionDetailR\vpermissions\x12%\n" +
+	"\x0ecorrelation_id\x18\x05 \x01(\tR\rcorrelationId\x120\n" +
+	"\x05error\x18\x06 \x01(\v2\x1a.permissions.v1.QueryErrorR\x05error\":\n" +
+	"\n" +
+	"QueryError\x12\x12\n" +
+	"\x04code\x18\x01 \x01(\x05R\x04code\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage2\xec\x01\n" +
+	"\x12PermissionsService\x12e\n" +
+	"\x10QueryPermissions\x12'.permissions.v1.QueryPermissionsRequest\x1a(.permissions.v1.QueryPermissionsResponse\x12o\n" +
+	"\x16StreamQueryPermissions\x12'.permissions.v1.QueryPermissionsRequest\x1a(.permissions.v1.QueryPermissionsResponse(\x010\x01B3Z1adapter/internal/grpc/permissionspb;permissionspbb\x06proto3"
+
+var (
+	file_permissions_v1_permissions_proto_rawDescOnce sync.Once
+	file_permissions_v1_permissions_proto_rawDescData []byte
+)
+
+func file_permissions_v1_permissions_proto_rawDescGZIP() []byte {
+	file_permissions_v1_permissions_proto_rawDescOnce.Do(func() {
+		file_permissions_v1_permissions_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_permissions_v1_permissions_proto_rawDesc), len(file_permissions_v1_permissions_proto_rawDesc)))
+	})
+	return file_permissions_v1_permissions_proto_rawDescData
+}
+
+var file_permissions_v1_permissions_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_permissions_v1_permissions_proto_goTypes = []any{
+	(*QueryPermissionsRequest)(nil),  // 0: permissions.v1.QueryPermissionsRequest
+	(*PermissionDetail)(nil),         // 1: permissions.v1.PermissionDetail
+	(*QueryPermissionsResponse)(nil), // 2: permissions.v1.QueryPermissionsResponse
+	(*QueryError)(nil),               // 3: permissions.v1.QueryError
+	(*timestamppb.Timestamp)(nil),    // 4: google.protobuf.Timestamp
+}
+var file_permissions_v1_permissions_proto_depIdxs = []int32{
+	4, // 0: permissions.v1.PermissionDetail.decided_at:type_name -> google.protobuf.Timestamp
+	4, // 1: permissions.v1.PermissionDetail.expires_at:type_name -> google.protobuf.Timestamp
+	1, // 2: permissions.v1.QueryPermissionsResponse.permissions:type_name -> permissions.v1.PermissionDetail
+	3, // 3: permissions.v1.QueryPermissionsResponse.error:type_name -> permissions.v1.QueryError
+	0, // 4: permissions.v1.PermissionsService.QueryPermissions:input_type -> permissions.v1.QueryPermissionsRequest
+	0, // 5: permissions.v1.PermissionsService.StreamQueryPermissions:input_type -> permissions.v1.QueryPermissionsRequest
+	2, // 6: permissions.v1.PermissionsService.QueryPermissions:output_type -> permissions.v1.QueryPermissionsResponse
+	2, // 7: permissions.v1.PermissionsService.StreamQueryPermissions:output_type -> permissions.v1.QueryPermissionsResponse
+	6, // [6:8] is the sub-list for method output_type
+	4, // [4:6] is the sub-list for method input_type
+	4, // [4:4] is the sub-list for extension type_name
+	4, // [4:4] is the sub-list for extension extendee
+	0, // [0:4] is the sub-list for field type_name
+}
+
+func init() { file_permissions_v1_permissions_proto_init() }
+func file_permissions_v1_permissions_proto_init() {
+	if File_permissions_v1_permissions_proto != nil {
+		return
+	}
+	file_permissions_v1_permissions_proto_msgTypes[1].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_permissions_v1_permissions_proto_rawDesc), len(file_permissions_v1_permissions_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   4,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_permissions_v1_permissions_proto_goTypes,
+		DependencyIndexes: file_permissions_v1_permissions_proto_depIdxs,
+		MessageInfos:      file_permissions_v1_permissions_proto_msgTypes,
+	}.Build()
+	File_permissions_v1_permissions_proto = out.File
+	file_permissions_v1_permissions_proto_goTypes = nil
+	file_permissions_v1_permissions_proto_depIdxs = nil
+}
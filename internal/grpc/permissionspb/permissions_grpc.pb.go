@@ -0,0 +1,170 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.28.3
+// source: permissions/v1/permissions.proto
+
+package permissionspb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	PermissionsService_QueryPermissions_FullMethodName       = "/permissions.v1.PermissionsService/QueryPermissions"
+	PermissionsService_StreamQueryPermissions_FullMethodName = "/permissions.v1.PermissionsService/StreamQueryPermissions"
+)
+
+// PermissionsServiceClient is the client API for PermissionsService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// PermissionsService answers BAP access checks for sellers. It mirrors
+// POST /v1/permissions/query on the HTTP API.
+type PermissionsServiceClient interface {
+	// QueryPermissions returns the decisions a BAP has for a set of sellers.
+	QueryPermissions(ctx context.Context, in *QueryPermissionsRequest, opts ...grpc.CallOption) (*QueryPermissionsResponse, error)
+	// StreamQueryPermissions answers a stream of queries over a single
+	// connection, one response per request in the order received. A request
+	// that cannot be answered gets a response carrying its error and the
+	// stream stays open.
+	StreamQueryPermissions(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[QueryPermissionsRequest, QueryPermissionsResponse], error)
+}
+
+type permissionsServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPermissionsServiceClient(cc grpc.ClientConnInterface) PermissionsServiceClient {
+	return &permissionsServiceClient{cc}
+}
+
+func (c *permissionsServiceClient) QueryPermissions(ctx context.Context, in *QueryPermissionsRequest, opts ...grpc.CallOption) (*QueryPermissionsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(QueryPermissionsResponse)
+	err := c.cc.Invoke(ctx, PermissionsService_QueryPermissions_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *permissionsServiceClient) StreamQueryPermissions(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[QueryPermissionsRequest, QueryPermissionsResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &PermissionsService_ServiceDesc.Streams[0], PermissionsService_StreamQueryPermissions_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[QueryPermissionsRequest, QueryPermissionsResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PermissionsService_StreamQueryPermissionsClient = grpc.BidiStreamingClient[QueryPermissionsRequest, QueryPermissionsResponse]
+
+// PermissionsServiceServer is the server API for PermissionsService service.
+// All implementations must embed UnimplementedPermissionsServiceServer
+// for forward compatibility.
+//
+// PermissionsService answers BAP access checks for sellers. It mirrors
+// POST /v1/permissions/query on the HTTP API.
+type PermissionsServiceServer interface {
+	// QueryPermissions returns the decisions a BAP has for a set of sellers.
+	QueryPermissions(context.Context, *QueryPermissionsRequest) (*QueryPermissionsResponse, error)
+	// StreamQueryPermissions answers a stream of queries over a single
+	// connection, one response per request in the order received. A request
+	// that cannot be answered gets a response carrying its error and the
+	// stream stays open.
+	StreamQueryPermissions(grpc.BidiStreamingServer[QueryPermissionsRequest, QueryPermissionsResponse]) error
+	mustEmbedUnimplementedPermissionsServiceServer()
+}
+
+// UnimplementedPermissionsServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedPermissionsServiceServer struct{}
+
+func (UnimplementedPermissionsServiceServer) QueryPermissions(context.Context, *QueryPermissionsRequest) (*QueryPermissionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method QueryPermissions not implemented")
+}
+func (UnimplementedPermissionsServiceServer) StreamQueryPermissions(grpc.BidiStreamingServer[QueryPermissionsRequest, QueryPermissionsResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamQueryPermissions not implemented")
+}
+func (UnimplementedPermissionsServiceServer) mustEmbedUnimplementedPermissionsServiceServer() {}
+func (UnimplementedPermissionsServiceServer) testEmbeddedByValue()                            {}
+
+// UnsafePermissionsServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PermissionsServiceServer will
+// result in compilation errors.
+type UnsafePermissionsServiceServer interface {
+	mustEmbedUnimplementedPermissionsServiceServer()
+}
+
+func RegisterPermissionsServiceServer(s grpc.ServiceRegistrar, srv PermissionsServiceServer) {
+	// If the following call pancis, it indicates UnimplementedPermissionsServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&PermissionsService_ServiceDesc, srv)
+}
+
+func _PermissionsService_QueryPermissions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QueryPermissionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PermissionsServiceServer).QueryPermissions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PermissionsService_QueryPermissions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PermissionsServiceServer).QueryPermissions(ctx, req.(*QueryPermissionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PermissionsService_StreamQueryPermissions_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(PermissionsServiceServer).StreamQueryPermissions(&grpc.GenericServerStream[QueryPermissionsRequest, QueryPermissionsResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PermissionsService_StreamQueryPermissionsServer = grpc.BidiStreamingServer[QueryPermissionsRequest, QueryPermissionsResponse]
+
+// PermissionsService_ServiceDesc is the grpc.ServiceDesc for PermissionsService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var PermissionsService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "permissions.v1.PermissionsService",
+	HandlerType: (*PermissionsServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "QueryPermissions",
+			Handler:    _PermissionsService_QueryPermissions_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamQueryPermissions",
+			Handler:       _PermissionsService_StreamQueryPermissions_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "permissions/v1/permissions.proto",
+}
@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"time"
+
 	catalogSyncPorts "adapter/internal/ports/catalog_sync"
 	"adapter/internal/shared/constants"
 	"adapter/internal/shared/utils"
@@ -26,6 +28,7 @@ func (h *CatalogSyncHandler) GetPendingCatalogSyncSellers(c *fiber.Ctx) error {
 	}
 	registryEnv := c.Query("registry_env", "preprod")
 	status := c.Query("status")
+	city := c.Query("city")
 	limit := c.QueryInt("limit", 100)
 	page := c.QueryInt("page", 1)
 	offset := (page - 1) * limit
@@ -33,7 +36,7 @@ func (h *CatalogSyncHandler) GetPendingCatalogSyncSellers(c *fiber.Ctx) error {
 		offset = 0
 	}
 
-	response, err := h.service.GetPendingCatalogSyncSellers(domain, registryEnv, status, limit, page, offset)
+	response, err := h.service.GetPendingCatalogSyncSellers(domain, registryEnv, status, city, limit, page, offset)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(utils.ApiResponse{
 			Success: false,
@@ -78,4 +81,107 @@ func (h *CatalogSyncHandler) GetSyncStatus(c *fiber.Ctx) error {
 		Message: "Sync status retrieved successfully",
 		Data:    response,
 	})
-}
\ No newline at end of file
+}
+
+func (h *CatalogSyncHandler) GetSellerChanges(c *fiber.Ctx) error {
+	sellerID := c.Params("seller_id")
+	domain := c.Query("domain")
+	registryEnv := c.Query("registry_env", "preprod") // Default to "preprod"
+	limit := c.QueryInt("limit", 50)
+	page := c.QueryInt("page", 1)
+	offset := (page - 1) * limit
+	if offset < 0 {
+		offset = 0
+	}
+
+	response, err := h.service.GetSellerChanges(sellerID, domain, registryEnv, limit, page, offset)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ApiResponse{
+			Success: false,
+			Message: constants.ErrGetSellerChanges,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.ApiResponse{
+		Success: true,
+		Message: "Seller changes retrieved successfully",
+		Data:    response,
+	})
+}
+
+func (h *CatalogSyncHandler) GetExpiringSellers(c *fiber.Ctx) error {
+	domain := c.Query("domain")
+	registryEnv := c.Query("registry_env", "preprod") // Default to "preprod"
+	within, err := time.ParseDuration(c.Query("within", "168h"))
+	if err != nil || within <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ApiResponse{
+			Success: false,
+			Message: constants.ErrInvalidExpiryWindow,
+		})
+	}
+	limit := c.QueryInt("limit", 100)
+	page := c.QueryInt("page", 1)
+	offset := (page - 1) * limit
+	if offset < 0 {
+		offset = 0
+	}
+
+	response, err := h.service.GetExpiringSellers(domain, registryEnv, within, limit, page, offset)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ApiResponse{
+			Success: false,
+			Message: constants.ErrGetExpiringSellers,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.ApiResponse{
+		Success: true,
+		Message: "Expiring sellers retrieved successfully",
+		Data:    response,
+	})
+}
+
+func (h *CatalogSyncHandler) GetSellerLocations(c *fiber.Ctx) error {
+	sellerID := c.Params("seller_id")
+	domain := c.Query("domain")
+	registryEnv := c.Query("registry_env", "preprod") // Default to "preprod"
+
+	response, err := h.service.GetSellerLocations(sellerID, domain, registryEnv)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(utils.ApiResponse{
+				Success: false,
+				Message: constants.ErrSellerLocationsNotFound,
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ApiResponse{
+			Success: false,
+			Message: constants.ErrGetSellerLocations,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.ApiResponse{
+		Success: true,
+		Message: "Seller locations retrieved successfully",
+		Data:    response,
+	})
+}
+
+func (h *CatalogSyncHandler) GetCityCoverage(c *fiber.Ctx) error {
+	domain := c.Query("domain")
+	registryEnv := c.Query("registry_env", "preprod") // Default to "preprod"
+
+	response, err := h.service.GetCityCoverage(domain, registryEnv)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ApiResponse{
+			Success: false,
+			Message: constants.ErrGetCityCoverage,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.ApiResponse{
+		Success: true,
+		Message: "City coverage retrieved successfully",
+		Data:    response,
+	})
+}
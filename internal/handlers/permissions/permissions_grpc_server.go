@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"adapter/internal/domain/permissions"
+	"adapter/internal/grpc/permissionspb"
+	permissionPorts "adapter/internal/ports/permissions"
+	"adapter/internal/shared/authctx"
+	"adapter/internal/shared/constants"
+	"adapter/internal/shared/log"
+)
+
+// PermissionsGRPCServer exposes the permissions query over gRPC, backed by the
+// same PermissionsService as the HTTP handler.
+type PermissionsGRPCServer struct {
+	permissionspb.UnimplementedPermissionsServiceServer
+	permissionsService *permissions.PermissionsService
+}
+
+func NewPermissionsGRPCServer(permissionsService *permissions.PermissionsService) *PermissionsGRPCServer {
+	return &PermissionsGRPCServer{permissionsService: permissionsService}
+}
+
+func (s *PermissionsGRPCServer) QueryPermissions(ctx context.Context, req *permissionspb.QueryPermissionsRequest) (*permissionspb.QueryPermissionsResponse, error) {
+	return s.queryPermissions(ctx, req)
+}
+
+func (s *PermissionsGRPCServer) StreamQueryPermissions(stream permissionspb.PermissionsService_StreamQueryPermissionsServer) error {
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		// A failed request is answered with its error so the rest of the
+		// stream is still served
+		resp, err := s.queryPermissions(stream.Context(), req)
+		if err != nil {
+			st := status.Convert(err)
+			resp = &permissionspb.QueryPermissionsResponse{
+				CorrelationId: req.GetCorrelationId(),
+				Error: &permissionspb.QueryError{
+					Code:    int32(st.Code()),
+					Message: st.Message(),
+				},
+			}
+		}
+		if err := stream.Send(resp); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *PermissionsGRPCServer) queryPermissions(ctx context.Context, req *permissionspb.QueryPermissionsRequest) (*permissionspb.QueryPermissionsResponse, error) {
+	if req.GetBapId() == "" || req.GetDomain() == "" || req.GetRegistryEnv() == "" || len(req.GetSellerIds()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, constants.ErrRequiredPermissionsFields)
+	}
+	if signer := authctx.SubscriberID(ctx); signer != "" && signer != req.GetBapId() {
+		return nil, status.Error(codes.PermissionDenied, constants.ErrBapIDMismatch)
+	}
+	if signer := authctx.SubscriberID(ctx); signer != "" && authctx.RegistryEnv(ctx) != req.GetRegistryEnv() {
+		return nil, status.Error(codes.PermissionDenied, constants.ErrRegistryEnvMismatch)
+	}
+
+	response, err := s.permissionsService.QueryPermissions(permissionPorts.PermissionsQueryRequest{
+		BapID:           req.GetBapId(),
+		Domain:          req.GetDomain(),
+		RegistryEnv:     req.GetRegistryEnv(),
+		SellerIDs:       req.GetSellerIds(),
+		ProviderIDs:     req.GetProviderIds(),
+		IncludeNoPolicy: req.GetIncludeNoPolicy(),
+	})
+	if err != nil {
+		log.Error(ctx, err, constants.ErrFailedToQueryPermissions)
+		return nil, status.Error(codes.Internal, constants.ErrFailedToQueryPermissions)
+	}
+
+	return toQueryPermissionsResponse(response, req.GetCorrelationId()), nil
+}
+
+func toQueryPermissionsResponse(response *permissionPorts.PermissionsQueryResponse, correlationID string) *permissionspb.QueryPermissionsResponse {
+	resp := &permissionspb.QueryPermissionsResponse{
+		BapStatus:     response.BapStatus,
+		Domain:        response.Domain,
+		RegistryEnv:   response.RegistryEnv,
+		CorrelationId: correlationID,
+		Permissions:   make([]*permissionspb.PermissionDetail, 0, len(response.Permissions)),
+	}
+
+	for _, p := range response.Permissions {
+		detail := &permissionspb.PermissionDetail{
+			SellerId:       p.SellerID,
+			Domain:         p.Domain,
+			RegistryEnv:    p.RegistryEnv,
+			BapId:          p.BapID,
+			ProviderId:     p.ProviderID,
+			Decision:       p.Decision,
+			PolicyLevel:    p.PolicyLevel,
+			DecisionSource: p.DecisionSource,
+		}
+		if p.DecidedAt != nil {
+			detail.DecidedAt = timestamppb.New(*p.DecidedAt)
+		}
+		if p.ExpiresAt != nil {
+			detail.ExpiresAt = timestamppb.New(*p.ExpiresAt)
+		}
+		resp.Permissions = append(resp.Permissions, detail)
+	}
+
+	return resp
+}
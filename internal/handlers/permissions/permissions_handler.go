@@ -3,6 +3,7 @@ package handlers
 import (
 	"adapter/internal/domain/permissions"
 	permissionPorts "adapter/internal/ports/permissions"
+	"adapter/internal/shared/authctx"
 	"adapter/internal/shared/constants"
 	"adapter/internal/shared/utils"
 	"github.com/gofiber/fiber/v2"
@@ -65,6 +66,22 @@ func (h *PermissionsHandler) QueryPermissions(c *fiber.Ctx) error {
 		})
 	}
 
+	// When ONDC signature auth is enabled, callers may only query as themselves
+	if subscriberID := authctx.SubscriberID(c.UserContext()); subscriberID != "" && subscriberID != req.BapID {
+		return c.Status(fiber.StatusForbidden).JSON(utils.ApiResponse{
+			Success: false,
+			Message: constants.ErrBapIDMismatch,
+		})
+	}
+
+	// and only in the registry env their signing key was resolved in
+	if subscriberID := authctx.SubscriberID(c.UserContext()); subscriberID != "" && authctx.RegistryEnv(c.UserContext()) != req.RegistryEnv {
+		return c.Status(fiber.StatusForbidden).JSON(utils.ApiResponse{
+			Success: false,
+			Message: constants.ErrRegistryEnvMismatch,
+		})
+	}
+
 	response, err := h.permissionsService.QueryPermissions(req)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(utils.ApiResponse{
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	permissionPorts "adapter/internal/ports/permissions"
+	"adapter/internal/shared/constants"
+	"adapter/internal/shared/utils"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+func (h *PermissionsHandler) SavePolicyTemplate(c *fiber.Ctx) error {
+	name := c.Params("name")
+
+	var req permissionPorts.PolicyTemplateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ApiResponse{
+			Success: false,
+			Message: constants.ErrInvalidRequestBody,
+		})
+	}
+
+	if name == "" || len(req.Entries) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ApiResponse{
+			Success: false,
+			Message: constants.ErrPolicyTemplateFieldsRequired,
+		})
+	}
+	for _, entry := range req.Entries {
+		decision := permissionPorts.AccessDecision(entry.Decision)
+		if entry.BapID == "" || (decision != permissionPorts.DecisionAllowed && decision != permissionPorts.DecisionDenied) {
+			return c.Status(fiber.StatusBadRequest).JSON(utils.ApiResponse{
+				Success: false,
+				Message: constants.ErrInvalidPolicyTemplateEntry,
+			})
+		}
+	}
+
+	response, err := h.permissionsService.SavePolicyTemplate(name, req)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ApiResponse{
+			Success: false,
+			Message: constants.ErrFailedToSavePolicyTemplate,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.ApiResponse{
+		Success: true,
+		Message: "Policy template saved successfully",
+		Data:    response,
+	})
+}
+
+func (h *PermissionsHandler) ListPolicyTemplates(c *fiber.Ctx) error {
+	response, err := h.permissionsService.ListPolicyTemplates()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ApiResponse{
+			Success: false,
+			Message: constants.ErrFailedToGetPolicyTemplates,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.ApiResponse{
+		Success: true,
+		Message: "Policy templates retrieved successfully",
+		Data:    fiber.Map{"templates": response},
+	})
+}
+
+func (h *PermissionsHandler) GetPolicyTemplate(c *fiber.Ctx) error {
+	response, err := h.permissionsService.GetPolicyTemplate(c.Params("name"))
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(utils.ApiResponse{
+				Success: false,
+				Message: constants.ErrPolicyTemplateNotFound,
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ApiResponse{
+			Success: false,
+			Message: constants.ErrFailedToGetPolicyTemplates,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.ApiResponse{
+		Success: true,
+		Message: "Policy template retrieved successfully",
+		Data:    response,
+	})
+}
+
+func (h *PermissionsHandler) DeletePolicyTemplate(c *fiber.Ctx) error {
+	if err := h.permissionsService.DeletePolicyTemplate(c.Params("name")); err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(utils.ApiResponse{
+				Success: false,
+				Message: constants.ErrPolicyTemplateNotFound,
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ApiResponse{
+			Success: false,
+			Message: constants.ErrFailedToDeletePolicyTemplate,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.ApiResponse{
+		Success: true,
+		Message: "Policy template deleted successfully",
+	})
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+
+	ondc "adapter/internal/domain/registry_sync"
+	ports "adapter/internal/ports/registry_sync"
+	"adapter/internal/shared/constants"
+	"adapter/internal/shared/utils"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+func (h *RegistrySyncHandler) ListHeldDeactivations(c *fiber.Ctx) error {
+	registryEnv := c.Query("registry_env")
+	status := c.Query("status", string(ports.DeactivationHoldStatusHeld))
+	limit := c.QueryInt("limit", 20)
+	page := c.QueryInt("page", 1)
+	offset := (page - 1) * limit
+	if offset < 0 {
+		offset = 0
+	}
+
+	response, err := h.ondcService.GetHeldDeactivations(registryEnv, status, limit, page, offset)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ApiResponse{
+			Success: false,
+			Message: constants.ErrGetHeldDeactivations,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.ApiResponse{
+		Success: true,
+		Message: "Held deactivations retrieved successfully",
+		Data:    response,
+	})
+}
+
+func (h *RegistrySyncHandler) GetHeldDeactivation(c *fiber.Ctx) error {
+	response, err := h.ondcService.GetHeldDeactivation(c.Params("hold_id"))
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(utils.ApiResponse{
+				Success: false,
+				Message: constants.ErrHeldDeactivationNotFound,
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ApiResponse{
+			Success: false,
+			Message: constants.ErrGetHeldDeactivations,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.ApiResponse{
+		Success: true,
+		Message: "Held deactivation retrieved successfully",
+		Data:    response,
+	})
+}
+
+func (h *RegistrySyncHandler) ApproveHeldDeactivation(c *fiber.Ctx) error {
+	return h.resolveHeldDeactivation(c, h.ondcService.ApproveHeldDeactivation, "Held deactivations approved")
+}
+
+func (h *RegistrySyncHandler) DiscardHeldDeactivation(c *fiber.Ctx) error {
+	return h.resolveHeldDeactivation(c, h.ondcService.DiscardHeldDeactivation, "Held deactivations discarded")
+}
+
+func (h *RegistrySyncHandler) resolveHeldDeactivation(c *fiber.Ctx, resolve func(context.Context, string) (*ports.HeldDeactivationResponse, error), message string) error {
+	response, err := resolve(c.UserContext(), c.Params("hold_id"))
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(utils.ApiResponse{
+				Success: false,
+				Message: constants.ErrHeldDeactivationNotFound,
+			})
+		}
+		if err == ondc.ErrDeactivationHoldResolved {
+			return c.Status(fiber.StatusConflict).JSON(utils.ApiResponse{
+				Success: false,
+				Message: constants.ErrHeldDeactivationResolved,
+			})
+		}
+		var alreadyRunning *ondc.SyncAlreadyRunningError
+		if errors.As(err, &alreadyRunning) {
+			return c.Status(fiber.StatusConflict).JSON(utils.ApiResponse{
+				Success: false,
+				Message: constants.ErrHeldDeactivationSyncRunning,
+				Data:    fiber.Map{"running": alreadyRunning.Running},
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ApiResponse{
+			Success: false,
+			Message: constants.ErrResolveHeldDeactivation,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.ApiResponse{
+		Success: true,
+		Message: message,
+		Data:    response,
+	})
+}
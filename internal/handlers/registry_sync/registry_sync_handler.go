@@ -1,11 +1,14 @@
 package handlers
 
 import (
+	"errors"
+
 	ondc "adapter/internal/domain/registry_sync"
 	ports "adapter/internal/ports/registry_sync"
 	"adapter/internal/shared/constants"
 	"adapter/internal/shared/utils"
 	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
 )
 
 type RegistrySyncHandler struct {
@@ -25,24 +28,172 @@ func (h *RegistrySyncHandler) SyncRegistry(c *fiber.Ctx) error {
 		})
 	}
 
-	if req.RegistryEnv == "" || len(req.Domains) == 0 {
+	// A replay defaults to the registry env and domains of the replayed run
+	if req.ReplayRunID == "" && (req.RegistryEnv == "" || len(req.Domains) == 0) {
 		return c.Status(fiber.StatusBadRequest).JSON(utils.ApiResponse{
 			Success: false,
 			Message: "registry_env and domains are required",
 		})
 	}
 
-	response, err := h.ondcService.SyncRegistry(req)
+	req.TriggerSource = ports.TriggerSourceAPI
+	response, err := h.ondcService.StartSyncJob(c.UserContext(), req)
 	if err != nil {
+		if errors.Is(err, ondc.ErrUnknownRegistryEnv) {
+			return c.Status(fiber.StatusBadRequest).JSON(utils.ApiResponse{
+				Success: false,
+				Message: constants.ErrUnknownRegistryEnv,
+			})
+		}
+		if errors.Is(err, ondc.ErrReplayRunNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(utils.ApiResponse{
+				Success: false,
+				Message: constants.ErrReplayRunNotFound,
+			})
+		}
+		if errors.Is(err, ondc.ErrReplayEnvMismatch) {
+			return c.Status(fiber.StatusBadRequest).JSON(utils.ApiResponse{
+				Success: false,
+				Message: constants.ErrReplayEnvMismatch,
+			})
+		}
+		if errors.Is(err, ondc.ErrLookupArchiveDisabled) {
+			return c.Status(fiber.StatusBadRequest).JSON(utils.ApiResponse{
+				Success: false,
+				Message: constants.ErrRegistryArchiveDisabled,
+			})
+		}
+		var alreadyRunning *ondc.SyncAlreadyRunningError
+		if errors.As(err, &alreadyRunning) {
+			return c.Status(fiber.StatusConflict).JSON(utils.ApiResponse{
+				Success: false,
+				Message: constants.ErrRegistrySyncAlreadyRunning,
+				Data:    fiber.Map{"running": alreadyRunning.Running},
+			})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(utils.ApiResponse{
 			Success: false,
 			Message: constants.ErrFailedToStartRegistrySync,
 		})
 	}
 
+	return c.Status(fiber.StatusAccepted).JSON(utils.ApiResponse{
+		Success: true,
+		Message: "Registry sync job queued successfully",
+		Data:    response,
+	})
+}
+
+func (h *RegistrySyncHandler) GetSyncJob(c *fiber.Ctx) error {
+	response, err := h.ondcService.GetSyncRun(c.Params("job_id"))
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(utils.ApiResponse{
+				Success: false,
+				Message: constants.ErrSyncJobNotFound,
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ApiResponse{
+			Success: false,
+			Message: constants.ErrGetSyncRuns,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.ApiResponse{
+		Success: true,
+		Message: "Registry sync job retrieved successfully",
+		Data:    response,
+	})
+}
+
+func (h *RegistrySyncHandler) CancelSyncJob(c *fiber.Ctx) error {
+	response, err := h.ondcService.CancelSyncJob(c.UserContext(), c.Params("job_id"))
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(utils.ApiResponse{
+				Success: false,
+				Message: constants.ErrSyncJobNotFound,
+			})
+		}
+		if err == ondc.ErrSyncJobFinished {
+			return c.Status(fiber.StatusConflict).JSON(utils.ApiResponse{
+				Success: false,
+				Message: constants.ErrSyncJobAlreadyFinished,
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ApiResponse{
+			Success: false,
+			Message: constants.ErrFailedToCancelSyncJob,
+		})
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(utils.ApiResponse{
+		Success: true,
+		Message: "Registry sync job cancellation requested",
+		Data:    response,
+	})
+}
+
+func (h *RegistrySyncHandler) ListSyncRuns(c *fiber.Ctx) error {
+	registryEnv := c.Query("registry_env")
+	limit := c.QueryInt("limit", 20)
+	page := c.QueryInt("page", 1)
+	offset := (page - 1) * limit
+	if offset < 0 {
+		offset = 0
+	}
+
+	response, err := h.ondcService.GetSyncRuns(registryEnv, limit, page, offset)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ApiResponse{
+			Success: false,
+			Message: constants.ErrGetSyncRuns,
+		})
+	}
+
 	return c.Status(fiber.StatusOK).JSON(utils.ApiResponse{
 		Success: true,
-		Message: "Registry sync completed successfully",
+		Message: "Registry sync runs retrieved successfully",
 		Data:    response,
 	})
-}
\ No newline at end of file
+}
+
+func (h *RegistrySyncHandler) GetSyncRun(c *fiber.Ctx) error {
+	response, err := h.ondcService.GetSyncRun(c.Params("run_id"))
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(utils.ApiResponse{
+				Success: false,
+				Message: constants.ErrSyncRunNotFound,
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ApiResponse{
+			Success: false,
+			Message: constants.ErrGetSyncRuns,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.ApiResponse{
+		Success: true,
+		Message: "Registry sync run retrieved successfully",
+		Data:    response,
+	})
+}
+
+func (h *RegistrySyncHandler) GetDomainSyncStatuses(c *fiber.Ctx) error {
+	registryEnv := c.Query("registry_env", "preprod") // Default to "preprod"
+
+	response, err := h.ondcService.GetDomainSyncStatuses(registryEnv)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ApiResponse{
+			Success: false,
+			Message: constants.ErrGetSyncRuns,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.ApiResponse{
+		Success: true,
+		Message: "Domain sync statuses retrieved successfully",
+		Data:    fiber.Map{"registry_env": registryEnv, "domains": response},
+	})
+}
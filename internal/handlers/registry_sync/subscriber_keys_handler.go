@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"adapter/internal/shared/constants"
+	"adapter/internal/shared/utils"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+func (h *RegistrySyncHandler) GetSubscriberKeys(c *fiber.Ctx) error {
+	subscriberID := c.Params("id")
+	registryEnv := c.Query("registry_env", "preprod") // Default to "preprod"
+
+	response, err := h.ondcService.GetSubscriberKeys(registryEnv, subscriberID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(utils.ApiResponse{
+				Success: false,
+				Message: constants.ErrSubscriberKeysNotFound,
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ApiResponse{
+			Success: false,
+			Message: constants.ErrGetSubscriberKeys,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.ApiResponse{
+		Success: true,
+		Message: "Subscriber keys retrieved successfully",
+		Data:    response,
+	})
+}
@@ -0,0 +1,177 @@
+package mockregistry
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"os"
+	"strings"
+)
+
+// Entry is a registry entry in fixture files: one subscriber in one domain and
+// city under one key, as returned by the legacy lookup.
+type Entry struct {
+	SubscriberID  string `json:"subscriber_id"`
+	UkID          string `json:"ukId"`
+	BrID          string `json:"br_id,omitempty"`
+	Type          string `json:"type"`
+	Domain        string `json:"domain"`
+	Country       string `json:"country"`
+	City          string `json:"city"`
+	SubscriberURL string `json:"subscriber_url,omitempty"`
+	MSN           bool   `json:"msn,omitempty"`
+	SigningKey    string `json:"signing_public_key"`
+	EncryptionKey string `json:"encr_public_key"`
+	Status        string `json:"status"`
+	ValidFrom     string `json:"valid_from"`
+	ValidUntil    string `json:"valid_until"`
+	Created       string `json:"created"`
+	Updated       string `json:"updated"`
+}
+
+// lookupRequest holds the lookup criteria the mock filters on. Page is a mock
+// extension selecting a page when pagination is enabled.
+type lookupRequest struct {
+	Country      string `json:"country"`
+	City         string `json:"city"`
+	Type         string `json:"type"`
+	Domain       string `json:"domain"`
+	SubscriberID string `json:"subscriber_id"`
+	UkID         string `json:"ukId"`
+	Page         int    `json:"page"`
+}
+
+// LoadFixtures reads a fixture file: a JSON array of entries.
+func LoadFixtures(path string) ([]Entry, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var entries []Entry
+	if err := json.Unmarshal(raw, &entries); err != nil {
+		return nil, fmt.Errorf("failed to decode fixture file %s: %w", path, err)
+	}
+	return entries, nil
+}
+
+// matches reports whether an entry satisfies the lookup criteria. Empty
+// criteria and a "*" city match everything.
+func (r lookupRequest) matches(e Entry) bool {
+	return matchField(r.Country, e.Country) &&
+		(r.City == "*" || matchField(r.City, e.City)) &&
+		matchField(r.Type, e.Type) &&
+		matchField(r.Domain, e.Domain) &&
+		matchField(r.SubscriberID, e.SubscriberID) &&
+		matchField(r.UkID, e.UkID)
+}
+
+func matchField(want, got string) bool {
+	return want == "" || strings.EqualFold(want, got)
+}
+
+// churned reports whether a subscriber is left out of a generation. Each
+// generation drops a different, reproducible share of the subscribers, so a
+// subscriber dropped from one generation usually returns in the next.
+func churned(subscriberID string, seed int64, generation int, share float64) bool {
+	if generation == 0 || share <= 0 {
+		return false
+	}
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%d|%d|%s", seed, generation, subscriberID)
+	return float64(h.Sum64())/math.MaxUint64 < share
+}
+
+// v2 lookup response shapes
+type entryV2 struct {
+	SubscriberID       string          `json:"subscriber_id"`
+	UkID               string          `json:"ukId"`
+	BrID               string          `json:"br_id,omitempty"`
+	Country            string          `json:"country"`
+	Status             string          `json:"status"`
+	Created            string          `json:"created"`
+	Updated            string          `json:"updated"`
+	Keys               []keyV2         `json:"keys"`
+	NetworkParticipant []participantV2 `json:"network_participant"`
+}
+
+type keyV2 struct {
+	UkID             string `json:"ukId"`
+	SigningPublicKey string `json:"signing_public_key"`
+	EncrPublicKey    string `json:"encr_public_key"`
+	ValidFrom        string `json:"valid_from"`
+	ValidUntil       string `json:"valid_until"`
+}
+
+type participantV2 struct {
+	SubscriberURL string   `json:"subscriber_url"`
+	Domain        string   `json:"domain"`
+	Type          string   `json:"type"`
+	MSN           bool     `json:"msn"`
+	CityCode      []string `json:"city_code"`
+}
+
+var participantTypes = map[string]string{
+	"BPP": "sellerApp",
+	"BAP": "buyerApp",
+	"BG":  "gateway",
+}
+
+// toV2 nests entries the way the v2.0 lookup does: one entry per subscriber
+// and key, with a network participant per domain listing its cities. Entry
+// order follows the first occurrence of each subscriber and key.
+func toV2(entries []Entry) []entryV2 {
+	result := []entryV2{}
+	index := make(map[string]int)
+	for _, e := range entries {
+		key := e.SubscriberID + "|" + e.UkID
+		i, ok := index[key]
+		if !ok {
+			i = len(result)
+			index[key] = i
+			result = append(result, entryV2{
+				SubscriberID: e.SubscriberID,
+				UkID:         e.UkID,
+				BrID:         e.BrID,
+				Country:      e.Country,
+				Status:       e.Status,
+				Created:      e.Created,
+				Updated:      e.Updated,
+				Keys: []keyV2{{
+					UkID:             e.UkID,
+					SigningPublicKey: e.SigningKey,
+					EncrPublicKey:    e.EncryptionKey,
+					ValidFrom:        e.ValidFrom,
+					ValidUntil:       e.ValidUntil,
+				}},
+			})
+		}
+
+		participants := result[i].NetworkParticipant
+		p := -1
+		for j := range participants {
+			if participants[j].Domain == e.Domain {
+				p = j
+				break
+			}
+		}
+		if p < 0 {
+			participantType, ok := participantTypes[strings.ToUpper(e.Type)]
+			if !ok {
+				participantType = e.Type
+			}
+			participants = append(participants, participantV2{
+				SubscriberURL: e.SubscriberURL,
+				Domain:        e.Domain,
+				Type:          participantType,
+				MSN:           e.MSN,
+			})
+			p = len(participants) - 1
+		}
+		if e.City != "" {
+			participants[p].CityCode = append(participants[p].CityCode, e.City)
+		}
+		result[i].NetworkParticipant = participants
+	}
+	return result
+}
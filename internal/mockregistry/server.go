@@ -0,0 +1,312 @@
+// Package mockregistry is a stand-in for the ONDC registry lookup API, serving
+// subscribers from fixture files so the registry sync can be developed and
+// tested without the preprod registry or registered keys.
+//
+// It answers the legacy lookup on /lookup and the v2.0 lookup on
+// /v2.0/lookup from the same fixtures, verifies request signatures against the
+// signing keys in the fixtures, and can inject errors, latency, pagination and
+// churn between sync runs. Besides cmd/mockregistry, tests can run it in
+// process:
+//
+//	srv, _ := mockregistry.New(mockregistry.Config{Fixtures: files})
+//	ts := httptest.NewServer(srv.Handler())
+//	defer ts.Close()
+package mockregistry
+
+import (
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+
+	"adapter/internal/shared/crypto"
+	"adapter/internal/shared/log"
+	"adapter/internal/shared/utils"
+)
+
+const (
+	signedHeaders           = "(created) (expires) digest"
+	defaultClockSkew        = 5 * time.Second
+	ondcAuthErrorType       = "CONTEXT-ERROR"
+	ondcInvalidSignatureErr = "10001"
+)
+
+// Config controls what the mock registry serves and which failures it
+// simulates.
+type Config struct {
+	// Fixtures lists fixture files, one per generation. The first is served
+	// until the registry advances; the last is kept once all are used.
+	Fixtures []string
+	// AdvanceEvery moves to the next generation after this many answered
+	// lookups, e.g. the number of domains a sync run looks up. Zero advances
+	// only on request.
+	AdvanceEvery int
+	// Churn drops this share (0-1) of subscribers from every generation after
+	// the first, picked reproducibly from Seed.
+	Churn float64
+	Seed  int64
+
+	// VerifySignatures rejects lookups that are not signed by a key listed
+	// in the fixtures or in Keys, keyed "subscriber_id|ukId".
+	VerifySignatures bool
+	Keys             map[string]string
+	ClockSkew        time.Duration
+
+	// Latency delays every lookup, plus a random share of LatencyJitter.
+	Latency       time.Duration
+	LatencyJitter time.Duration
+
+	// FailFirst fails the first lookups outright and ErrorRate (0-1) fails a
+	// random share of the rest, with ErrorStatus (503 by default) and, when
+	// set, a Retry-After header.
+	FailFirst   int
+	ErrorRate   float64
+	ErrorStatus int
+	RetryAfter  time.Duration
+
+	// PageSize splits responses into pages of this many entries, selected
+	// with a "page" field in the request body or query. Zero disables
+	// pagination.
+	PageSize int
+}
+
+// Stats counts the lookups the mock registry has seen.
+type Stats struct {
+	Generation int `json:"generation"`
+	Requests   int `json:"requests"`
+	Answered   int `json:"answered"`
+	Failed     int `json:"failed"`
+	Rejected   int `json:"rejected"`
+}
+
+// Server is a mock ONDC registry.
+type Server struct {
+	cfg         Config
+	generations [][]Entry
+	keys        map[string]string
+	crypto      *crypto.ONDCCrypto
+	app         *fiber.App
+
+	mu    sync.Mutex
+	rand  *rand.Rand
+	stats Stats
+}
+
+// New loads the fixtures and builds the mock registry.
+func New(cfg Config) (*Server, error) {
+	if len(cfg.Fixtures) == 0 {
+		return nil, errors.New("mock registry requires at least one fixture file")
+	}
+	if cfg.ErrorStatus == 0 {
+		cfg.ErrorStatus = fiber.StatusServiceUnavailable
+	}
+	if cfg.ClockSkew <= 0 {
+		cfg.ClockSkew = defaultClockSkew
+	}
+
+	s := &Server{
+		cfg:    cfg,
+		keys:   make(map[string]string, len(cfg.Keys)),
+		crypto: crypto.NewONDCCrypto(),
+		rand:   rand.New(rand.NewSource(cfg.Seed)),
+	}
+	for _, path := range cfg.Fixtures {
+		entries, err := LoadFixtures(path)
+		if err != nil {
+			return nil, err
+		}
+		s.generations = append(s.generations, entries)
+		for _, e := range entries {
+			if e.SigningKey != "" {
+				s.keys[e.SubscriberID+"|"+e.UkID] = e.SigningKey
+			}
+		}
+	}
+	for keyID, publicKey := range cfg.Keys {
+		s.keys[keyID] = publicKey
+	}
+
+	s.app = fiber.New(fiber.Config{DisableStartupMessage: true})
+	s.app.Post("/lookup", s.lookup(false))
+	s.app.Post("/v2.0/lookup", s.lookup(true))
+	s.app.Post("/mock/advance", func(c *fiber.Ctx) error {
+		return c.JSON(fiber.Map{"generation": s.Advance()})
+	})
+	s.app.Get("/mock/stats", func(c *fiber.Ctx) error {
+		return c.JSON(s.Stats())
+	})
+	return s, nil
+}
+
+// App returns the mock registry's fiber app.
+func (s *Server) App() *fiber.App {
+	return s.app
+}
+
+// Handler returns the mock registry as an http.Handler, e.g. for httptest.
+func (s *Server) Handler() http.Handler {
+	return adaptor.FiberApp(s.app)
+}
+
+// Listen serves the mock registry on addr.
+func (s *Server) Listen(addr string) error {
+	return s.app.Listen(addr)
+}
+
+// Serve serves the mock registry on ln.
+func (s *Server) Serve(ln net.Listener) error {
+	return s.app.Listener(ln)
+}
+
+// Shutdown stops the mock registry.
+func (s *Server) Shutdown() error {
+	return s.app.Shutdown()
+}
+
+// Advance moves to the next generation and returns it.
+func (s *Server) Advance() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stats.Generation++
+	return s.stats.Generation
+}
+
+// Stats returns the lookups seen so far.
+func (s *Server) Stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stats
+}
+
+func (s *Server) lookup(v2 bool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.UserContext()
+
+		s.mu.Lock()
+		s.stats.Requests++
+		fail := s.stats.Requests <= s.cfg.FailFirst || (s.cfg.ErrorRate > 0 && s.rand.Float64() < s.cfg.ErrorRate)
+		var jitter time.Duration
+		if s.cfg.LatencyJitter > 0 {
+			jitter = time.Duration(s.rand.Int63n(int64(s.cfg.LatencyJitter)))
+		}
+		if fail {
+			s.stats.Failed++
+		}
+		s.mu.Unlock()
+
+		if delay := s.cfg.Latency + jitter; delay > 0 {
+			time.Sleep(delay)
+		}
+		if fail {
+			if s.cfg.RetryAfter > 0 {
+				c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(s.cfg.RetryAfter.Seconds())))
+			}
+			return c.Status(s.cfg.ErrorStatus).JSON(fiber.Map{"error": "simulated registry failure"})
+		}
+
+		if s.cfg.VerifySignatures {
+			if reason := s.verifySignature(c); reason != "" {
+				log.Warnf(ctx, "Mock registry rejected lookup: %s", reason)
+				s.mu.Lock()
+				s.stats.Rejected++
+				s.mu.Unlock()
+				c.Set(fiber.HeaderWWWAuthenticate, `Signature realm="mockregistry",headers="`+signedHeaders+`"`)
+				return c.Status(fiber.StatusUnauthorized).JSON(utils.NewONDCNack(ondcAuthErrorType, ondcInvalidSignatureErr, reason))
+			}
+		}
+
+		var req lookupRequest
+		if len(c.Body()) > 0 {
+			if err := json.Unmarshal(c.Body(), &req); err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid lookup request"})
+			}
+		}
+		if req.Page == 0 {
+			req.Page = c.QueryInt("page", 1)
+		}
+
+		s.mu.Lock()
+		generation := s.stats.Generation
+		s.stats.Answered++
+		if s.cfg.AdvanceEvery > 0 && s.stats.Answered%s.cfg.AdvanceEvery == 0 {
+			s.stats.Generation++
+		}
+		s.mu.Unlock()
+
+		entries := s.entries(generation, req)
+		if v2 {
+			return writePage(c, toV2(entries), s.cfg.PageSize, req.Page)
+		}
+		return writePage(c, entries, s.cfg.PageSize, req.Page)
+	}
+}
+
+// entries returns the entries of a generation matching a lookup.
+func (s *Server) entries(generation int, req lookupRequest) []Entry {
+	fixtures := s.generations[min(generation, len(s.generations)-1)]
+	entries := []Entry{}
+	for _, e := range fixtures {
+		if req.matches(e) && !churned(e.SubscriberID, s.cfg.Seed, generation, s.cfg.Churn) {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// verifySignature checks the Authorization header and returns why it was
+// rejected, or "" when it is valid.
+func (s *Server) verifySignature(c *fiber.Ctx) string {
+	header := c.Get(fiber.HeaderAuthorization)
+	if header == "" {
+		return "authorization header is missing"
+	}
+	auth, err := crypto.ParseAuthorizationHeader(header)
+	if err != nil {
+		return err.Error()
+	}
+	if auth.Algorithm != "ed25519" || (auth.Headers != "" && auth.Headers != signedHeaders) {
+		return "unsupported signature algorithm or headers"
+	}
+
+	now := time.Now()
+	created := time.Unix(int64(auth.Created), 0)
+	expires := time.Unix(int64(auth.Expires), 0)
+	if created.After(now.Add(s.cfg.ClockSkew)) || expires.Before(now.Add(-s.cfg.ClockSkew)) || !expires.After(created) {
+		return "signature has expired"
+	}
+
+	publicKey, ok := s.keys[auth.SubscriberID+"|"+auth.UniqueKeyID]
+	if !ok {
+		return "unknown key " + auth.SubscriberID + "|" + auth.UniqueKeyID
+	}
+	valid, err := s.crypto.VerifyRequest(publicKey, c.Body(), auth.Created, auth.Expires, auth.Signature)
+	if err != nil || !valid {
+		return "signature verification failed"
+	}
+	return ""
+}
+
+// writePage writes one page of a lookup response. Pages are 1-based; the
+// X-Total-Count and X-Next-Page headers describe the rest.
+func writePage[T any](c *fiber.Ctx, items []T, pageSize, page int) error {
+	if pageSize > 0 {
+		if page < 1 {
+			page = 1
+		}
+		c.Set("X-Total-Count", strconv.Itoa(len(items)))
+		start := min((page-1)*pageSize, len(items))
+		end := min(start+pageSize, len(items))
+		if end < len(items) {
+			c.Set("X-Next-Page", strconv.Itoa(page+1))
+		}
+		items = items[start:end]
+	}
+	return c.JSON(items)
+}
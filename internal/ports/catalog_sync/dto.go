@@ -8,6 +8,7 @@ type PendingCatalogSyncSellersResponse struct {
 	Domain       string       `json:"domain"`
 	RegistryEnv  string       `json:"registry_env"`
 	StatusFilter []string     `json:"status_filter"`
+	City         string       `json:"city,omitempty"`
 	Sellers      []SellerInfo `json:"sellers"`
 	Page         PageInfo     `json:"page"`
 }
@@ -38,4 +39,69 @@ type CatalogSyncStatusResponse struct {
 	LastError          *string    `json:"last_error"`
 	SyncVersion        int64      `json:"sync_version"`
 	RegistryLastSeenAt time.Time  `json:"registry_last_seen_at"`
+	SellerActive       bool       `json:"seller_active"`
+	RegistryValidUntil *time.Time `json:"registry_valid_until"`
+}
+
+// FieldChange describes how a single registry field of a seller changed
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// SellerChangeResponse describes a recorded change to a seller's registry entry
+type SellerChangeResponse struct {
+	Domain      string        `json:"domain"`
+	RegistryEnv string        `json:"registry_env"`
+	RunID       string        `json:"run_id"`
+	ChangeType  string        `json:"change_type"`
+	Changes     []FieldChange `json:"changes"`
+	ChangedAt   time.Time     `json:"changed_at"`
+}
+
+// SellerChangesResponse defines the response body for the seller changelog API
+type SellerChangesResponse struct {
+	SellerID    string                 `json:"seller_id"`
+	RegistryEnv string                 `json:"registry_env"`
+	Changes     []SellerChangeResponse `json:"changes"`
+	Page        PageInfo               `json:"page"`
+}
+
+// ExpiringSellerInfo describes a seller whose registry validity ends soon
+type ExpiringSellerInfo struct {
+	SellerID   string    `json:"seller_id"`
+	Domain     string    `json:"domain"`
+	Status     string    `json:"status"`
+	ValidUntil time.Time `json:"valid_until"`
+	LastSeenAt time.Time `json:"registry_last_seen_at"`
+}
+
+// ExpiringSellersResponse defines the response body for the expiring sellers API
+type ExpiringSellersResponse struct {
+	Domain      string               `json:"domain,omitempty"`
+	RegistryEnv string               `json:"registry_env"`
+	ExpiringBy  time.Time            `json:"expiring_by"`
+	Sellers     []ExpiringSellerInfo `json:"sellers"`
+	Page        PageInfo             `json:"page"`
+}
+
+// SellerLocationsResponse defines the response body for the seller locations API
+type SellerLocationsResponse struct {
+	SellerID    string           `json:"seller_id"`
+	RegistryEnv string           `json:"registry_env"`
+	Locations   []SellerLocation `json:"locations"`
+}
+
+// CityCoverage counts the active sellers serving a city
+type CityCoverage struct {
+	City    string `json:"city"`
+	Sellers int    `json:"sellers"`
+}
+
+// CityCoverageResponse defines the response body for the city coverage API
+type CityCoverageResponse struct {
+	Domain      string         `json:"domain,omitempty"`
+	RegistryEnv string         `json:"registry_env"`
+	Cities      []CityCoverage `json:"cities"`
 }
@@ -2,28 +2,122 @@ package ports
 
 import "time"
 
+// Lifecycle is our view of a seller's registry status.
+type Lifecycle string
+
+const (
+	LifecycleActive   Lifecycle = "ACTIVE"
+	LifecyclePending  Lifecycle = "PENDING"
+	LifecycleInactive Lifecycle = "INACTIVE"
+)
+
+// Seller is a registry subscriber synced from the ONDC registry. Lifecycle is
+// derived from the registry status. ValidFrom and ValidUntil are nil when the
+// registry did not provide a usable value; a seller is only Active while its
+// lifecycle is active and it is inside its validity window.
 type Seller struct {
-	SellerID      string    `json:"seller_id" gorm:"primaryKey;column:seller_id;type:text"`
-	Domain        string    `json:"domain" gorm:"primaryKey;column:domain;type:text"`
-	RegistryEnv   string    `json:"registry_env" gorm:"primaryKey;column:registry_env;type:text"`
-	Status        string    `json:"status" gorm:"column:status;type:text"`
-	Type          string    `json:"type" gorm:"column:type;type:text"`
-	SubscriberURL string    `json:"subscriber_url" gorm:"column:subscriber_url;type:text"`
-	Country       string    `json:"country" gorm:"column:country;type:text"`
-	City          string    `json:"city" gorm:"column:city;type:text"`
-	ValidFrom     time.Time `json:"valid_from" gorm:"column:valid_from;type:timestamptz"`
-	ValidUntil    time.Time `json:"valid_until" gorm:"column:valid_until;type:timestamptz"`
-	Active        bool      `json:"active" gorm:"column:active;type:boolean"`
-	RegistryRaw   string    `json:"registry_raw" gorm:"column:registry_raw;type:jsonb"`
-	LastSeenInReg time.Time `json:"last_seen_in_reg" gorm:"column:last_seen_in_reg;type:timestamptz"`
-	CreatedAt     time.Time `json:"created_at" gorm:"autoCreateTime"`
-	UpdatedAt     time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+	SellerID      string     `json:"seller_id" gorm:"primaryKey;column:seller_id;type:text"`
+	Domain        string     `json:"domain" gorm:"primaryKey;column:domain;type:text"`
+	RegistryEnv   string     `json:"registry_env" gorm:"primaryKey;column:registry_env;type:text"`
+	Status        string     `json:"status" gorm:"column:status;type:text"`
+	Lifecycle     Lifecycle  `json:"lifecycle" gorm:"column:lifecycle;type:text"`
+	Type          string     `json:"type" gorm:"column:type;type:text"`
+	SubscriberURL string     `json:"subscriber_url" gorm:"column:subscriber_url;type:text"`
+	MSN           bool       `json:"msn" gorm:"column:msn;type:boolean;not null;default:false"`
+	Country       string     `json:"country" gorm:"column:country;type:text"`
+	City          string     `json:"city" gorm:"column:city;type:text"`
+	ValidFrom     *time.Time `json:"valid_from" gorm:"column:valid_from;type:timestamptz"`
+	ValidUntil    *time.Time `json:"valid_until" gorm:"column:valid_until;type:timestamptz"`
+	Active        bool       `json:"active" gorm:"column:active;type:boolean"`
+	RegistryRaw   string     `json:"registry_raw" gorm:"column:registry_raw;type:jsonb"`
+	ContentHash   string     `json:"content_hash" gorm:"column:content_hash;type:text"`
+	LastSeenInReg time.Time  `json:"last_seen_in_reg" gorm:"column:last_seen_in_reg;type:timestamptz"`
+	DeactivatedAt *time.Time `json:"deactivated_at" gorm:"column:deactivated_at;type:timestamptz"`
+	ReactivatedAt *time.Time `json:"reactivated_at" gorm:"column:reactivated_at;type:timestamptz"`
+	CreatedAt     time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt     time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
 }
 
 func (Seller) TableName() string {
 	return "sellers"
 }
 
+// WithinValidity reports whether t falls inside the seller's registry validity
+// window. Missing bounds are treated as open.
+func (s Seller) WithinValidity(t time.Time) bool {
+	if s.ValidFrom != nil && t.Before(*s.ValidFrom) {
+		return false
+	}
+	if s.ValidUntil != nil && !t.Before(*s.ValidUntil) {
+		return false
+	}
+	return true
+}
+
+// SellerLocation is a city a seller serves under one of its registry keys.
+// The registry lists a seller once per city and key; Primary marks the
+// locations of the entry the seller row was built from.
+type SellerLocation struct {
+	SellerID      string     `json:"seller_id" gorm:"primaryKey;column:seller_id;type:text"`
+	Domain        string     `json:"domain" gorm:"primaryKey;column:domain;type:text"`
+	RegistryEnv   string     `json:"registry_env" gorm:"primaryKey;column:registry_env;type:text"`
+	City          string     `json:"city" gorm:"primaryKey;column:city;type:text;index:idx_seller_locations_city"`
+	UkID          string     `json:"ukId" gorm:"primaryKey;column:uk_id;type:text"`
+	Country       string     `json:"country" gorm:"column:country;type:text"`
+	Status        string     `json:"status" gorm:"column:status;type:text"`
+	SubscriberURL string     `json:"subscriber_url" gorm:"column:subscriber_url;type:text"`
+	ValidFrom     *time.Time `json:"valid_from" gorm:"column:valid_from;type:timestamptz"`
+	ValidUntil    *time.Time `json:"valid_until" gorm:"column:valid_until;type:timestamptz"`
+	Primary       bool       `json:"primary" gorm:"column:is_primary;type:boolean;not null;default:false"`
+	UpdatedAt     time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+func (SellerLocation) TableName() string {
+	return "seller_locations"
+}
+
+type SellerChangeType string
+
+const (
+	SellerChangeInserted    SellerChangeType = "INSERTED"
+	SellerChangeUpdated     SellerChangeType = "UPDATED"
+	SellerChangeDeactivated SellerChangeType = "DEACTIVATED"
+	SellerChangeReactivated SellerChangeType = "REACTIVATED"
+)
+
+// SellerChange records a change to a seller's registry entry detected during a
+// registry sync. Changes holds the field-level diff as a JSON array of
+// FieldChange.
+type SellerChange struct {
+	ID          uint64           `gorm:"primaryKey;column:id;autoIncrement"`
+	SellerID    string           `gorm:"column:seller_id;type:text;index:idx_seller_changes_lookup,priority:1"`
+	Domain      string           `gorm:"column:domain;type:text;index:idx_seller_changes_lookup,priority:2"`
+	RegistryEnv string           `gorm:"column:registry_env;type:text;index:idx_seller_changes_lookup,priority:3"`
+	RunID       string           `gorm:"column:run_id;type:text"`
+	ChangeType  SellerChangeType `gorm:"column:change_type;type:text"`
+	Changes     string           `gorm:"column:changes;type:jsonb"`
+	ChangedAt   time.Time        `gorm:"column:changed_at;type:timestamptz;index:idx_seller_changes_lookup,priority:4,sort:desc"`
+}
+
+func (SellerChange) TableName() string {
+	return "seller_changes"
+}
+
+// PendingPolicyTemplates marks a new seller whose auto-apply policy templates
+// have not been applied yet. The marker is written in the same transaction as
+// the seller and cleared once the templates are applied, so a failed
+// application is retried by the next sync.
+type PendingPolicyTemplates struct {
+	SellerID    string    `gorm:"primaryKey;column:seller_id;type:text"`
+	Domain      string    `gorm:"primaryKey;column:domain;type:text"`
+	RegistryEnv string    `gorm:"primaryKey;column:registry_env;type:text"`
+	CreatedAt   time.Time `gorm:"autoCreateTime"`
+}
+
+func (PendingPolicyTemplates) TableName() string {
+	return "seller_pending_policy_templates"
+}
+
 type CatalogStatus string
 
 const (
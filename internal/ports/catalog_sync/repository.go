@@ -1,13 +1,59 @@
 package ports
 
+import (
+	"context"
+	"time"
+)
+
 type SellerRepository interface {
-	InsertSellers(sellers []Seller) error
-	UpdateSellers(sellers []Seller) error
+	InsertSellers(ctx context.Context, sellers []Seller) error
+	UpdateSellers(ctx context.Context, sellers []Seller) error
+	TouchSellers(ctx context.Context, sellerIDs []string, domain, registryEnv string, seenAt time.Time) error
 	GetAllSellers() ([]Seller, error)
 	GetSellerByID(sellerID, domain, registryEnv string) (*Seller, error)
-	GetPendingSellers(domain, registryEnv, status string, limit, offset int) ([]SellerInfo, error)
-	GetSellersByDomainAndRegistry(domain, registryEnv string) ([]Seller, error)
-	DeactivateSellers(sellerIDs []string, domain, registryEnv string) error
-	UpsertCatalogState(state *SellerCatalogState) error
+	GetPendingSellers(domain, registryEnv, status, city string, limit, offset int) ([]SellerInfo, error)
+	GetSellersByDomainAndRegistry(ctx context.Context, domain, registryEnv string) ([]Seller, error)
+	DeactivateSellers(ctx context.Context, sellerIDs []string, domain, registryEnv string) error
+	DeactivateUnseenSellers(ctx context.Context, sellerIDs []string, domain, registryEnv string, seenBefore time.Time) ([]Seller, error)
+	GetExpiredSellers(ctx context.Context, now time.Time) ([]Seller, error)
+	DeactivateExpiredSellers(ctx context.Context, sellerIDs []string, domain, registryEnv string, now time.Time) ([]Seller, error)
+	CountActiveSellers(ctx context.Context, domain, registryEnv string) (int, error)
+	GetExpiringSellers(domain, registryEnv string, before time.Time, limit, offset int) ([]Seller, error)
+	UpsertCatalogState(ctx context.Context, state *SellerCatalogState) error
+	EnsureCatalogStates(ctx context.Context, states []SellerCatalogState) error
 	GetSellerCatalogState(sellerID, domain, registryEnv string) (*SellerCatalogState, error)
+	InsertSellerChanges(ctx context.Context, changes []SellerChange) error
+	GetSellerChanges(sellerID, domain, registryEnv string, limit, offset int) ([]SellerChange, error)
+	ReplaceSellerLocations(ctx context.Context, sellerIDs []string, domain, registryEnv, city string, locations []SellerLocation) error
+	GetSellerLocations(sellerID, domain, registryEnv string) ([]SellerLocation, error)
+	GetCityCoverage(domain, registryEnv string) ([]CityCoverage, error)
+	ApplySellerSync(ctx context.Context, writes SellerSyncWrites) error
+	GetPendingPolicyTemplateSellers(ctx context.Context, domain, registryEnv string) ([]string, error)
+	ClearPendingPolicyTemplates(ctx context.Context, sellerIDs []string, domain, registryEnv string) error
+}
+
+// SellerSyncWrites are the writes of one domain sync, applied together by
+// ApplySellerSync so a seller row is never stored without its locations and
+// changelog.
+type SellerSyncWrites struct {
+	Domain      string
+	RegistryEnv string
+	SeenAt      time.Time
+
+	Insert []Seller
+	Update []Seller
+	// Touch lists unchanged sellers whose last seen time is set to SeenAt
+	Touch []string
+	// LocationSellerIDs lists the sellers whose locations are replaced with
+	// Locations. When LocationCity is set only their locations in that city
+	// are replaced.
+	LocationSellerIDs []string
+	LocationCity      string
+	Locations         []SellerLocation
+	CatalogStates     []SellerCatalogState
+	Deactivate        []string
+	Changes           []SellerChange
+	// PendingPolicyTemplates lists the new sellers whose policy templates
+	// are still to be applied
+	PendingPolicyTemplates []string
 }
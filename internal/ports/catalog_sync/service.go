@@ -8,31 +8,95 @@ import (
 	"fmt"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
+	"time"
 )
 
 type Service interface {
-	GetPendingCatalogSyncSellers(domain, registryEnv, status string, limit, page, offset int) (*PendingCatalogSyncSellersResponse, error)
+	GetPendingCatalogSyncSellers(domain, registryEnv, status, city string, limit, page, offset int) (*PendingCatalogSyncSellersResponse, error)
 	GetSyncStatus(sellerID, domain, registryEnv string) (*CatalogSyncStatusResponse, error)
+	GetSellerChanges(sellerID, domain, registryEnv string, limit, page, offset int) (*SellerChangesResponse, error)
+	GetExpiringSellers(domain, registryEnv string, within time.Duration, limit, page, offset int) (*ExpiringSellersResponse, error)
+	GetSellerLocations(sellerID, domain, registryEnv string) (*SellerLocationsResponse, error)
+	GetCityCoverage(domain, registryEnv string) (*CityCoverageResponse, error)
+}
+
+// sellerValidityCondition restricts a query on sellers aliased as s to those
+// inside their registry validity window.
+const sellerValidityCondition = "(s.valid_from IS NULL OR s.valid_from <= NOW()) AND (s.valid_until IS NULL OR s.valid_until > NOW())"
+
+// sellerInCityCondition restricts a query on sellers aliased as s to those
+// with a registry location in the given city.
+const sellerInCityCondition = "EXISTS (SELECT 1 FROM seller_locations l WHERE l.seller_id = s.seller_id AND l.domain = s.domain AND l.registry_env = s.registry_env AND l.city = ?)"
+
+const (
+	// DefaultBatchSize is the number of rows written per statement when no
+	// batch size is configured.
+	DefaultBatchSize = 1000
+
+	// maxBindParams is the PostgreSQL limit on bind parameters per statement.
+	maxBindParams = 65535
+	// sellerColumns is the number of columns bound per row of sellers.
+	sellerColumns = 20
+)
+
+// sellerUpsertColumns are the columns rewritten when an incoming seller
+// already exists. Every column is listed so cleared validity dates and
+// deactivation are written too; the key and created_at are left alone.
+var sellerUpsertColumns = []string{
+	"status", "lifecycle", "type", "subscriber_url", "msn", "country", "city",
+	"valid_from", "valid_until", "active", "registry_raw", "content_hash",
+	"last_seen_in_reg", "deactivated_at", "reactivated_at", "updated_at",
 }
 
 type GormRepository struct {
-	db *gorm.DB
+	db        *gorm.DB
+	batchSize int
 }
 
 func NewGormRepository(db *gorm.DB) *GormRepository {
-	return &GormRepository{db: db}
+	return &GormRepository{db: db, batchSize: DefaultBatchSize}
+}
+
+// WithBatchSize sets how many rows each bulk write sends per statement. Sizes
+// that would exceed the PostgreSQL bind parameter limit are capped.
+func (r *GormRepository) WithBatchSize(size int) *GormRepository {
+	if size <= 0 {
+		size = DefaultBatchSize
+	}
+	if size > maxBindParams/sellerColumns {
+		size = maxBindParams / sellerColumns
+	}
+	r.batchSize = size
+	return r
+}
+
+func (r *GormRepository) InsertSellers(ctx context.Context, sellers []Seller) error {
+	log.Info(ctx, fmt.Sprintf("Attempting to insert %d new sellers in batches of %d...", len(sellers), r.batchSize))
+	if len(sellers) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).CreateInBatches(&sellers, r.batchSize).Error
 }
 
-func (r *GormRepository) InsertSellers(sellers []Seller) error {
-	log.Info(context.Background(), fmt.Sprintf("Attempting to insert %d new sellers...", len(sellers)))
-	return r.db.Create(&sellers).Error
+// UpdateSellers writes existing sellers back with one multi-row
+// INSERT ... ON CONFLICT DO UPDATE per batch, all inside a single transaction.
+func (r *GormRepository) UpdateSellers(ctx context.Context, sellers []Seller) error {
+	log.Info(ctx, fmt.Sprintf("Attempting to update %d existing sellers in batches of %d...", len(sellers), r.batchSize))
+	if len(sellers) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "seller_id"}, {Name: "domain"}, {Name: "registry_env"}},
+		DoUpdates: clause.AssignmentColumns(sellerUpsertColumns),
+	}).CreateInBatches(&sellers, r.batchSize).Error
 }
 
-func (r *GormRepository) UpdateSellers(sellers []Seller) error {
-	log.Info(context.Background(), fmt.Sprintf("Attempting to update %d existing sellers...", len(sellers)))
-	return r.db.Transaction(func(tx *gorm.DB) error {
-		for _, seller := range sellers {
-			if err := tx.Model(&Seller{}).Where("seller_id = ? AND domain = ? AND registry_env = ?", seller.SellerID, seller.Domain, seller.RegistryEnv).Updates(seller).Error; err != nil {
+// TouchSellers records that unchanged sellers were still present in the
+// registry, without rewriting the rest of their row.
+func (r *GormRepository) TouchSellers(ctx context.Context, sellerIDs []string, domain, registryEnv string, seenAt time.Time) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, ids := range chunk(sellerIDs, r.batchSize) {
+			if err := tx.Model(&Seller{}).Where("seller_id IN ? AND domain = ? AND registry_env = ?", ids, domain, registryEnv).UpdateColumn("last_seen_in_reg", seenAt).Error; err != nil {
 				return err
 			}
 		}
@@ -56,31 +120,74 @@ func (r *GormRepository) GetSellerByID(sellerID, domain, registryEnv string) (*S
 	return &seller, nil
 }
 
-func (r *GormRepository) GetSellersByDomainAndRegistry(domain, registryEnv string) ([]Seller, error) {
+// GetSellersByDomainAndRegistry returns every seller of the domain, including
+// inactive ones, so a sync can recognise sellers it has seen before.
+func (r *GormRepository) GetSellersByDomainAndRegistry(ctx context.Context, domain, registryEnv string) ([]Seller, error) {
 	var sellers []Seller
-	if err := r.db.Where("domain = ? AND registry_env = ? AND active = ?", domain, registryEnv, true).Find(&sellers).Error; err != nil {
+	if err := r.db.WithContext(ctx).Where("domain = ? AND registry_env = ?", domain, registryEnv).Find(&sellers).Error; err != nil {
 		return nil, err
 	}
 	return sellers, nil
 }
 
-func (r *GormRepository) DeactivateSellers(sellerIDs []string, domain, registryEnv string) error {
-	return r.db.Model(&Seller{}).Where("seller_id IN ? AND domain = ? AND registry_env = ?", sellerIDs, domain, registryEnv).Update("active", false).Error
+func (r *GormRepository) DeactivateSellers(ctx context.Context, sellerIDs []string, domain, registryEnv string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, ids := range chunk(sellerIDs, r.batchSize) {
+			if err := tx.Model(&Seller{}).Where("seller_id IN ? AND domain = ? AND registry_env = ?", ids, domain, registryEnv).Updates(deactivation()).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DeactivateUnseenSellers deactivates the given sellers that are still active
+// and have not been seen in the registry since seenBefore, and returns the
+// sellers it deactivated.
+func (r *GormRepository) DeactivateUnseenSellers(ctx context.Context, sellerIDs []string, domain, registryEnv string, seenBefore time.Time) ([]Seller, error) {
+	var deactivated []Seller
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, ids := range chunk(sellerIDs, r.batchSize) {
+			var sellers []Seller
+			if err := tx.Model(&sellers).Clauses(clause.Returning{}).
+				Where("seller_id IN ? AND domain = ? AND registry_env = ?", ids, domain, registryEnv).
+				Where("active = ? AND last_seen_in_reg < ?", true, seenBefore).
+				Updates(deactivation()).Error; err != nil {
+				return err
+			}
+			deactivated = append(deactivated, sellers...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deactivated, nil
 }
 
-func (r *GormRepository) UpsertCatalogState(state *SellerCatalogState) error {
-	return r.db.Clauses(clause.OnConflict{
+func (r *GormRepository) UpsertCatalogState(ctx context.Context, state *SellerCatalogState) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
 		Columns:   []clause.Column{{Name: "seller_id"}, {Name: "domain"}, {Name: "registry_env"}},
 		DoUpdates: clause.AssignmentColumns([]string{"status", "last_pull_at", "last_success_at", "last_error", "sync_version", "updated_at"}),
 	}).Create(state).Error
 }
 
-func (r *GormRepository) GetPendingSellers(domain, registryEnv, status string, limit, offset int) ([]SellerInfo, error) {
+// EnsureCatalogStates creates catalog states that do not exist yet, leaving
+// existing states and their sync progress untouched.
+func (r *GormRepository) EnsureCatalogStates(ctx context.Context, states []SellerCatalogState) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(&states, r.batchSize).Error
+}
+
+func (r *GormRepository) GetPendingSellers(domain, registryEnv, status, city string, limit, offset int) ([]SellerInfo, error) {
 	var sellers []SellerInfo
 	query := r.db.Table("sellers as s").
 		Select("s.seller_id, scs.status, scs.last_pull_at, scs.last_success_at, scs.last_error").
 		Joins("LEFT JOIN seller_catalog_state scs ON s.seller_id = scs.seller_id AND s.domain = scs.domain AND s.registry_env = scs.registry_env").
-		Where("s.domain = ? AND s.registry_env = ? AND s.active = ?", domain, registryEnv, true)
+		Where("s.domain = ? AND s.registry_env = ? AND s.active = ?", domain, registryEnv, true).
+		Where(sellerValidityCondition)
+	if city != "" {
+		query = query.Where(sellerInCityCondition, city)
+	}
 
 	var statusConditions []string
 	var statusValues []interface{}
@@ -128,3 +235,221 @@ func (r *GormRepository) GetSellerCatalogState(sellerID, domain, registryEnv str
 	}
 	return &state, nil
 }
+
+func (r *GormRepository) InsertSellerChanges(ctx context.Context, changes []SellerChange) error {
+	return r.db.WithContext(ctx).CreateInBatches(&changes, r.batchSize).Error
+}
+
+func (r *GormRepository) GetSellerChanges(sellerID, domain, registryEnv string, limit, offset int) ([]SellerChange, error) {
+	var changes []SellerChange
+	query := r.db.Where("seller_id = ? AND registry_env = ?", sellerID, registryEnv)
+	if domain != "" {
+		query = query.Where("domain = ?", domain)
+	}
+	if err := query.Order("changed_at DESC, id DESC").Limit(limit + 1).Offset(offset).Find(&changes).Error; err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// GetExpiredSellers returns the IDs, domains and registry envs of every active
+// seller whose registry validity ended before now.
+func (r *GormRepository) GetExpiredSellers(ctx context.Context, now time.Time) ([]Seller, error) {
+	var sellers []Seller
+	err := r.db.WithContext(ctx).Select("seller_id", "domain", "registry_env").
+		Where("active = ? AND valid_until IS NOT NULL AND valid_until <= ?", true, now).
+		Order("registry_env, domain, seller_id").Find(&sellers).Error
+	return sellers, err
+}
+
+// DeactivateExpiredSellers deactivates the given sellers that are still active
+// and whose registry validity ended before now, and returns the sellers it
+// deactivated.
+func (r *GormRepository) DeactivateExpiredSellers(ctx context.Context, sellerIDs []string, domain, registryEnv string, now time.Time) ([]Seller, error) {
+	var deactivated []Seller
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, ids := range chunk(sellerIDs, r.batchSize) {
+			var sellers []Seller
+			if err := tx.Model(&sellers).Clauses(clause.Returning{}).
+				Where("seller_id IN ? AND domain = ? AND registry_env = ?", ids, domain, registryEnv).
+				Where("active = ? AND valid_until IS NOT NULL AND valid_until <= ?", true, now).
+				Updates(deactivation()).Error; err != nil {
+				return err
+			}
+			deactivated = append(deactivated, sellers...)
+		}
+		return nil
+	})
+	return deactivated, err
+}
+
+// CountActiveSellers counts the active sellers of a domain.
+func (r *GormRepository) CountActiveSellers(ctx context.Context, domain, registryEnv string) (int, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&Seller{}).
+		Where("domain = ? AND registry_env = ? AND active = ?", domain, registryEnv, true).
+		Count(&count).Error
+	return int(count), err
+}
+
+// GetExpiringSellers returns active sellers whose registry validity ends
+// between now and before, soonest first.
+func (r *GormRepository) GetExpiringSellers(domain, registryEnv string, before time.Time, limit, offset int) ([]Seller, error) {
+	var sellers []Seller
+	query := r.db.Table("sellers as s").
+		Where("s.registry_env = ? AND s.active = ?", registryEnv, true).
+		Where(sellerValidityCondition).
+		Where("s.valid_until <= ?", before)
+	if domain != "" {
+		query = query.Where("s.domain = ?", domain)
+	}
+	if err := query.Order("s.valid_until, s.seller_id").Limit(limit + 1).Offset(offset).Find(&sellers).Error; err != nil {
+		return nil, err
+	}
+	return sellers, nil
+}
+
+// ReplaceSellerLocations replaces the locations of the given sellers with
+// locations: all of them, or only those in city when it is set.
+func (r *GormRepository) ReplaceSellerLocations(ctx context.Context, sellerIDs []string, domain, registryEnv, city string, locations []SellerLocation) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, ids := range chunk(sellerIDs, r.batchSize) {
+			query := tx.Where("seller_id IN ? AND domain = ? AND registry_env = ?", ids, domain, registryEnv)
+			if city != "" {
+				query = query.Where("city = ?", city)
+			}
+			if err := query.Delete(&SellerLocation{}).Error; err != nil {
+				return err
+			}
+		}
+		if len(locations) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(&locations, r.batchSize).Error
+	})
+}
+
+// ApplySellerSync applies the writes of a domain sync in one transaction.
+func (r *GormRepository) ApplySellerSync(ctx context.Context, writes SellerSyncWrites) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		txRepo := &GormRepository{db: tx, batchSize: r.batchSize}
+		if len(writes.Insert) > 0 {
+			if err := txRepo.InsertSellers(ctx, writes.Insert); err != nil {
+				return fmt.Errorf("failed to insert new sellers: %w", err)
+			}
+		}
+		if len(writes.Update) > 0 {
+			if err := txRepo.UpdateSellers(ctx, writes.Update); err != nil {
+				return fmt.Errorf("failed to update existing sellers: %w", err)
+			}
+		}
+		if len(writes.Touch) > 0 {
+			if err := txRepo.TouchSellers(ctx, writes.Touch, writes.Domain, writes.RegistryEnv, writes.SeenAt); err != nil {
+				return fmt.Errorf("failed to update last seen time of unchanged sellers: %w", err)
+			}
+		}
+		if len(writes.LocationSellerIDs) > 0 {
+			if err := txRepo.ReplaceSellerLocations(ctx, writes.LocationSellerIDs, writes.Domain, writes.RegistryEnv, writes.LocationCity, writes.Locations); err != nil {
+				return fmt.Errorf("failed to record seller locations: %w", err)
+			}
+		}
+		if len(writes.CatalogStates) > 0 {
+			if err := txRepo.EnsureCatalogStates(ctx, writes.CatalogStates); err != nil {
+				return fmt.Errorf("failed to insert catalog state: %w", err)
+			}
+		}
+		if len(writes.Deactivate) > 0 {
+			if err := txRepo.DeactivateSellers(ctx, writes.Deactivate, writes.Domain, writes.RegistryEnv); err != nil {
+				return fmt.Errorf("failed to deactivate sellers: %w", err)
+			}
+		}
+		if len(writes.Changes) > 0 {
+			if err := txRepo.InsertSellerChanges(ctx, writes.Changes); err != nil {
+				return fmt.Errorf("failed to record seller changes: %w", err)
+			}
+		}
+		if len(writes.PendingPolicyTemplates) > 0 {
+			pending := make([]PendingPolicyTemplates, 0, len(writes.PendingPolicyTemplates))
+			for _, id := range writes.PendingPolicyTemplates {
+				pending = append(pending, PendingPolicyTemplates{SellerID: id, Domain: writes.Domain, RegistryEnv: writes.RegistryEnv})
+			}
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(&pending, r.batchSize).Error; err != nil {
+				return fmt.Errorf("failed to record pending policy templates: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// GetPendingPolicyTemplateSellers returns the sellers of a domain whose policy
+// templates are still to be applied.
+func (r *GormRepository) GetPendingPolicyTemplateSellers(ctx context.Context, domain, registryEnv string) ([]string, error) {
+	var sellerIDs []string
+	if err := r.db.WithContext(ctx).Model(&PendingPolicyTemplates{}).
+		Where("domain = ? AND registry_env = ?", domain, registryEnv).
+		Order("seller_id").Pluck("seller_id", &sellerIDs).Error; err != nil {
+		return nil, err
+	}
+	return sellerIDs, nil
+}
+
+// ClearPendingPolicyTemplates removes the pending markers of sellers whose
+// policy templates have been applied.
+func (r *GormRepository) ClearPendingPolicyTemplates(ctx context.Context, sellerIDs []string, domain, registryEnv string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, ids := range chunk(sellerIDs, r.batchSize) {
+			if err := tx.Where("seller_id IN ? AND domain = ? AND registry_env = ?", ids, domain, registryEnv).Delete(&PendingPolicyTemplates{}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *GormRepository) GetSellerLocations(sellerID, domain, registryEnv string) ([]SellerLocation, error) {
+	var locations []SellerLocation
+	query := r.db.Where("seller_id = ? AND registry_env = ?", sellerID, registryEnv)
+	if domain != "" {
+		query = query.Where("domain = ?", domain)
+	}
+	if err := query.Order("domain, is_primary DESC, city, uk_id").Find(&locations).Error; err != nil {
+		return nil, err
+	}
+	return locations, nil
+}
+
+// GetCityCoverage counts the active, valid sellers with a registry location in
+// each city.
+func (r *GormRepository) GetCityCoverage(domain, registryEnv string) ([]CityCoverage, error) {
+	var coverage []CityCoverage
+	query := r.db.Table("seller_locations as l").
+		Select("l.city, COUNT(DISTINCT s.seller_id) AS sellers").
+		Joins("JOIN sellers s ON s.seller_id = l.seller_id AND s.domain = l.domain AND s.registry_env = l.registry_env").
+		Where("l.registry_env = ? AND s.active = ?", registryEnv, true).
+		Where(sellerValidityCondition)
+	if domain != "" {
+		query = query.Where("l.domain = ?", domain)
+	}
+	if err := query.Group("l.city").Order("sellers DESC, l.city").Scan(&coverage).Error; err != nil {
+		return nil, err
+	}
+	return coverage, nil
+}
+
+// deactivation is the column update that deactivates a seller.
+func deactivation() map[string]interface{} {
+	return map[string]interface{}{"active": false, "deactivated_at": time.Now()}
+}
+
+// chunk splits ids into consecutive slices of at most size elements so that
+// IN lists stay below the bind parameter limit.
+func chunk(ids []string, size int) [][]string {
+	var chunks [][]string
+	for size < len(ids) {
+		ids, chunks = ids[size:], append(chunks, ids[:size])
+	}
+	if len(ids) > 0 {
+		chunks = append(chunks, ids)
+	}
+	return chunks
+}
@@ -0,0 +1,217 @@
+package ports
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// The seller write benchmarks compare the legacy write path of the registry
+// sync against the batched one on a real PostgreSQL database. They write
+// synthetic sellers under a dedicated registry env, which is cleared around
+// every benchmark, so they can be pointed at a development database:
+//
+//	DATABASE_URL=postgres://... go test ./internal/ports/catalog_sync -run '^$' -bench Sellers -args -sellers 50000
+//
+// Without DATABASE_URL the benchmarks are skipped.
+
+const (
+	benchDomain      = "ONDC:BENCH"
+	benchRegistryEnv = "benchmark"
+)
+
+var benchSellerCount = flag.Int("sellers", 20000, "number of synthetic sellers written per benchmark iteration")
+
+var benchBatchSizes = []int{100, DefaultBatchSize, maxBindParams / sellerColumns}
+
+func BenchmarkInsertSellers(b *testing.B) {
+	db := openBenchDB(b)
+	ctx := context.Background()
+
+	// A single statement with more than 65535 bind parameters is rejected,
+	// which is what the legacy insert ran into on large registries
+	if *benchSellerCount*sellerColumns <= maxBindParams {
+		b.Run("legacy", func(b *testing.B) {
+			b.StopTimer()
+			for i := 0; i < b.N; i++ {
+				clearBenchSellers(b, db)
+				sellers := syntheticSellers(*benchSellerCount, "v1")
+				b.StartTimer()
+				if err := db.WithContext(ctx).Create(&sellers).Error; err != nil {
+					b.Fatalf("legacy insert failed: %v", err)
+				}
+				b.StopTimer()
+			}
+		})
+	}
+
+	for _, batchSize := range benchBatchSizes {
+		b.Run(fmt.Sprintf("batched/%d", batchSize), func(b *testing.B) {
+			repo := NewGormRepository(db).WithBatchSize(batchSize)
+			b.StopTimer()
+			for i := 0; i < b.N; i++ {
+				clearBenchSellers(b, db)
+				sellers := syntheticSellers(*benchSellerCount, "v1")
+				b.StartTimer()
+				if err := repo.InsertSellers(ctx, sellers); err != nil {
+					b.Fatalf("batched insert failed: %v", err)
+				}
+				b.StopTimer()
+			}
+		})
+	}
+}
+
+func BenchmarkUpdateSellers(b *testing.B) {
+	db := openBenchDB(b)
+	ctx := context.Background()
+	seedBenchSellers(b, db)
+
+	// The legacy path issued one UPDATE per seller
+	b.Run("legacy", func(b *testing.B) {
+		b.StopTimer()
+		for i := 0; i < b.N; i++ {
+			sellers := syntheticSellers(*benchSellerCount, fmt.Sprintf("legacy-%d", i))
+			b.StartTimer()
+			err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+				for _, seller := range sellers {
+					if err := tx.Model(&Seller{}).Where("seller_id = ? AND domain = ? AND registry_env = ?", seller.SellerID, seller.Domain, seller.RegistryEnv).Select("*").Omit("seller_id", "domain", "registry_env", "created_at").Updates(&seller).Error; err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+			b.StopTimer()
+			if err != nil {
+				b.Fatalf("legacy update failed: %v", err)
+			}
+		}
+	})
+
+	for _, batchSize := range benchBatchSizes {
+		b.Run(fmt.Sprintf("batched/%d", batchSize), func(b *testing.B) {
+			repo := NewGormRepository(db).WithBatchSize(batchSize)
+			b.StopTimer()
+			for i := 0; i < b.N; i++ {
+				sellers := syntheticSellers(*benchSellerCount, fmt.Sprintf("batched-%d", i))
+				b.StartTimer()
+				if err := repo.UpdateSellers(ctx, sellers); err != nil {
+					b.Fatalf("batched update failed: %v", err)
+				}
+				b.StopTimer()
+			}
+		})
+	}
+}
+
+func BenchmarkTouchSellers(b *testing.B) {
+	db := openBenchDB(b)
+	ctx := context.Background()
+	ids := sellerIDs(seedBenchSellers(b, db))
+
+	if len(ids) <= maxBindParams {
+		b.Run("legacy", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if err := db.WithContext(ctx).Model(&Seller{}).Where("seller_id IN ? AND domain = ? AND registry_env = ?", ids, benchDomain, benchRegistryEnv).UpdateColumn("last_seen_in_reg", time.Now()).Error; err != nil {
+					b.Fatalf("legacy touch failed: %v", err)
+				}
+			}
+		})
+	}
+
+	for _, batchSize := range benchBatchSizes {
+		b.Run(fmt.Sprintf("batched/%d", batchSize), func(b *testing.B) {
+			repo := NewGormRepository(db).WithBatchSize(batchSize)
+			for i := 0; i < b.N; i++ {
+				if err := repo.TouchSellers(ctx, ids, benchDomain, benchRegistryEnv, time.Now()); err != nil {
+					b.Fatalf("batched touch failed: %v", err)
+				}
+			}
+		})
+	}
+}
+
+// openBenchDB connects to DATABASE_URL, skipping the benchmark without one.
+func openBenchDB(b *testing.B) *gorm.DB {
+	b.Helper()
+	dsn := os.Getenv("DATABASE_URL")
+	if dsn == "" {
+		b.Skip("DATABASE_URL is not set")
+	}
+
+	// Statement logging would dominate the timings of the per-row path
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		b.Fatalf("failed to connect to the database: %v", err)
+	}
+	if err := db.AutoMigrate(&Seller{}); err != nil {
+		b.Fatalf("failed to migrate sellers: %v", err)
+	}
+	clearBenchSellers(b, db)
+	b.Cleanup(func() {
+		clearBenchSellers(b, db)
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+// seedBenchSellers stores the synthetic sellers the update benchmarks rewrite.
+func seedBenchSellers(b *testing.B, db *gorm.DB) []Seller {
+	b.Helper()
+	sellers := syntheticSellers(*benchSellerCount, "seed")
+	if err := NewGormRepository(db).InsertSellers(context.Background(), sellers); err != nil {
+		b.Fatalf("failed to seed sellers: %v", err)
+	}
+	return sellers
+}
+
+func clearBenchSellers(b *testing.B, db *gorm.DB) {
+	b.Helper()
+	if err := db.Where("registry_env = ?", benchRegistryEnv).Delete(&Seller{}).Error; err != nil {
+		b.Fatalf("failed to clear benchmark sellers: %v", err)
+	}
+}
+
+func syntheticSellers(count int, version string) []Seller {
+	now := time.Now()
+	validUntil := now.AddDate(1, 0, 0)
+	sellers := make([]Seller, count)
+	for i := range sellers {
+		id := fmt.Sprintf("bench-seller-%06d.example.com", i)
+		sellers[i] = Seller{
+			SellerID:      id,
+			Domain:        benchDomain,
+			RegistryEnv:   benchRegistryEnv,
+			Status:        "SUBSCRIBED",
+			Lifecycle:     LifecycleActive,
+			Type:          "BPP",
+			SubscriberURL: "https://" + id + "/ondc",
+			Country:       "IND",
+			City:          "std:080",
+			ValidFrom:     &now,
+			ValidUntil:    &validUntil,
+			Active:        true,
+			RegistryRaw:   fmt.Sprintf(`{"subscriber_id":%q,"version":%q}`, id, version),
+			ContentHash:   id + "-" + version,
+			LastSeenInReg: now,
+		}
+	}
+	return sellers
+}
+
+func sellerIDs(sellers []Seller) []string {
+	ids := make([]string, len(sellers))
+	for i, seller := range sellers {
+		ids[i] = seller.SellerID
+	}
+	return ids
+}
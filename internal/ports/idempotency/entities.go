@@ -0,0 +1,32 @@
+package ports
+
+import "time"
+
+type IdempotencyStatus string
+
+const (
+	IdempotencyStatusInProgress IdempotencyStatus = "IN_PROGRESS"
+	IdempotencyStatusCompleted  IdempotencyStatus = "COMPLETED"
+)
+
+// IdempotencyRecord is a claimed Idempotency-Key. Key scopes the client's key
+// to the caller, method and path, so callers never see each other's
+// responses. Owner identifies the request holding the claim.
+type IdempotencyRecord struct {
+	Key            string            `gorm:"primaryKey;column:idempotency_key;type:text"`
+	Caller         string            `gorm:"column:caller;type:text"`
+	Owner          string            `gorm:"column:owner;type:text"`
+	Method         string            `gorm:"column:method;type:text"`
+	Path           string            `gorm:"column:path;type:text"`
+	RequestHash    string            `gorm:"column:request_hash;type:text"`
+	Status         IdempotencyStatus `gorm:"column:status;type:text"`
+	ResponseStatus int               `gorm:"column:response_status;type:integer"`
+	ContentType    string            `gorm:"column:content_type;type:text"`
+	ResponseBody   []byte            `gorm:"column:response_body;type:bytea"`
+	CreatedAt      time.Time         `gorm:"column:created_at;type:timestamptz;autoCreateTime"`
+	ExpiresAt      time.Time         `gorm:"column:expires_at;type:timestamptz;index"`
+}
+
+func (IdempotencyRecord) TableName() string {
+	return "idempotency_keys"
+}
@@ -0,0 +1,14 @@
+package ports
+
+import "time"
+
+type IdempotencyRepository interface {
+	CreateIdempotencyRecord(record *IdempotencyRecord) (bool, error)
+	GetIdempotencyRecord(key string) (*IdempotencyRecord, error)
+	CompleteIdempotencyRecord(key, owner string, responseStatus int, contentType string, responseBody []byte) error
+	// DeleteIdempotencyRecord deletes the claim only while it is still held by owner
+	DeleteIdempotencyRecord(key, owner string) error
+	// ReleaseIdempotencyRecord frees an in-progress claim held by owner
+	ReleaseIdempotencyRecord(key, owner string) error
+	DeleteExpiredIdempotencyRecords(before time.Time) (int64, error)
+}
@@ -0,0 +1,55 @@
+package ports
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type GormRepository struct {
+	db *gorm.DB
+}
+
+func NewGormRepository(db *gorm.DB) *GormRepository {
+	return &GormRepository{db: db}
+}
+
+// CreateIdempotencyRecord claims the key and reports whether this call created it.
+func (r *GormRepository) CreateIdempotencyRecord(record *IdempotencyRecord) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(record)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *GormRepository) GetIdempotencyRecord(key string) (*IdempotencyRecord, error) {
+	var record IdempotencyRecord
+	if err := r.db.First(&record, "idempotency_key = ?", key).Error; err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+func (r *GormRepository) CompleteIdempotencyRecord(key, owner string, responseStatus int, contentType string, responseBody []byte) error {
+	return r.db.Model(&IdempotencyRecord{}).Where("idempotency_key = ? AND owner = ?", key, owner).Updates(map[string]interface{}{
+		"status":          IdempotencyStatusCompleted,
+		"response_status": responseStatus,
+		"content_type":    contentType,
+		"response_body":   responseBody,
+	}).Error
+}
+
+func (r *GormRepository) DeleteIdempotencyRecord(key, owner string) error {
+	return r.db.Where("idempotency_key = ? AND owner = ?", key, owner).Delete(&IdempotencyRecord{}).Error
+}
+
+func (r *GormRepository) ReleaseIdempotencyRecord(key, owner string) error {
+	return r.db.Where("idempotency_key = ? AND owner = ? AND status = ?", key, owner, IdempotencyStatusInProgress).Delete(&IdempotencyRecord{}).Error
+}
+
+func (r *GormRepository) DeleteExpiredIdempotencyRecords(before time.Time) (int64, error) {
+	result := r.db.Where("expires_at < ?", before).Delete(&IdempotencyRecord{})
+	return result.RowsAffected, result.Error
+}
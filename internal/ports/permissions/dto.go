@@ -8,6 +8,7 @@ type PermissionsUpdateRequest struct {
 	Domain         string     `json:"domain"`
 	RegistryEnv    string     `json:"registry_env"`
 	BapID          string     `json:"bap_id"`
+	ProviderID     string     `json:"provider_id,omitempty"`
 	Decision       string     `json:"decision"`
 	DecisionSource string     `json:"decision_source"`
 	Reason         *string    `json:"reason"`
@@ -20,6 +21,7 @@ type PermissionsUpdateResponse struct {
 	Domain      string `json:"domain"`
 	RegistryEnv string `json:"registry_env"`
 	BapID       string `json:"bap_id"`
+	ProviderID  string `json:"provider_id,omitempty"`
 	Decision    string `json:"decision"`
 	Stored      bool   `json:"stored"`
 }
@@ -30,6 +32,7 @@ type PermissionsQueryRequest struct {
 	Domain          string   `json:"domain"`
 	RegistryEnv     string   `json:"registry_env"`
 	SellerIDs       []string `json:"seller_ids"`
+	ProviderIDs     []string `json:"provider_ids,omitempty"`
 	IncludeNoPolicy bool     `json:"include_no_policy"`
 }
 
@@ -39,7 +42,9 @@ type PermissionDetail struct {
 	Domain         string     `json:"domain"`
 	RegistryEnv    string     `json:"registry_env"`
 	BapID          string     `json:"bap_id"`
+	ProviderID     string     `json:"provider_id,omitempty"`
 	Decision       string     `json:"decision"`
+	PolicyLevel    string     `json:"policy_level,omitempty"`
 	DecisionSource *string    `json:"decision_source,omitempty"`
 	DecidedAt      *time.Time `json:"decided_at,omitempty"`
 	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
@@ -52,3 +57,30 @@ type PermissionsQueryResponse struct {
 	RegistryEnv string             `json:"registry_env"`
 	Permissions []PermissionDetail `json:"permissions"`
 }
+
+// PolicyTemplateEntryDTO defines a single BAP decision within a policy template
+type PolicyTemplateEntryDTO struct {
+	Domain   string  `json:"domain,omitempty"`
+	BapID    string  `json:"bap_id"`
+	Decision string  `json:"decision"`
+	Reason   *string `json:"reason,omitempty"`
+}
+
+// PolicyTemplateRequest defines the request body for the PUT /v1/policy-templates/:name API
+type PolicyTemplateRequest struct {
+	Description *string                  `json:"description"`
+	RegistryEnv string                   `json:"registry_env"`
+	AutoApply   bool                     `json:"auto_apply"`
+	Entries     []PolicyTemplateEntryDTO `json:"entries"`
+}
+
+// PolicyTemplateResponse defines the response body for the policy template APIs
+type PolicyTemplateResponse struct {
+	Name        string                   `json:"name"`
+	Description *string                  `json:"description,omitempty"`
+	RegistryEnv string                   `json:"registry_env,omitempty"`
+	AutoApply   bool                     `json:"auto_apply"`
+	Entries     []PolicyTemplateEntryDTO `json:"entries"`
+	CreatedAt   time.Time                `json:"created_at"`
+	UpdatedAt   time.Time                `json:"updated_at"`
+}
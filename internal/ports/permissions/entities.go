@@ -24,13 +24,26 @@ const (
 	SourceSellerAck      DecisionSource = "SELLER_ACK"
 	SourceSellerNack     DecisionSource = "SELLER_NACK"
 	SourceManualOverride DecisionSource = "MANUAL_OVERRIDE"
+	SourcePolicyTemplate DecisionSource = "POLICY_TEMPLATE"
 )
 
+// PolicyLevel tells whether an effective decision came from a seller-wide
+// policy or from a policy for one provider within the seller.
+type PolicyLevel string
+
+const (
+	PolicyLevelSeller   PolicyLevel = "SELLER"
+	PolicyLevelProvider PolicyLevel = "PROVIDER"
+)
+
+// BapAccessPolicy is a BAP decision for a seller. An empty ProviderID is the
+// seller-level decision; a non-empty one overrides it for that provider.
 type BapAccessPolicy struct {
 	SellerID       string         `gorm:"primaryKey;column:seller_id;type:text"`
 	Domain         string         `gorm:"primaryKey;column:domain;type:text"`
 	RegistryEnv    string         `gorm:"primaryKey;column:registry_env;type:text"`
 	BapID          string         `gorm:"primaryKey;column:bap_id;type:text"`
+	ProviderID     string         `gorm:"primaryKey;column:provider_id;type:text;not null;default:''"`
 	Decision       AccessDecision `gorm:"column:decision;type:text"`
 	DecisionSource DecisionSource `gorm:"column:decision_source;type:text"`
 	DecidedAt      time.Time      `gorm:"column:decided_at;type:timestamptz"`
@@ -42,3 +55,34 @@ type BapAccessPolicy struct {
 func (BapAccessPolicy) TableName() string {
 	return "bap_access_policy"
 }
+
+// PolicyTemplate is a named set of BAP decisions that can be applied to sellers
+// in bulk. Templates with AutoApply set are applied to sellers newly discovered
+// by a registry sync; an empty RegistryEnv matches every environment.
+type PolicyTemplate struct {
+	Name        string                `gorm:"primaryKey;column:name;type:text"`
+	Description *string               `gorm:"column:description;type:text"`
+	RegistryEnv string                `gorm:"column:registry_env;type:text"`
+	AutoApply   bool                  `gorm:"column:auto_apply;type:boolean"`
+	Entries     []PolicyTemplateEntry `gorm:"foreignKey:TemplateName;references:Name;constraint:OnDelete:CASCADE"`
+	CreatedAt   time.Time             `gorm:"column:created_at;type:timestamptz;autoCreateTime"`
+	UpdatedAt   time.Time             `gorm:"column:updated_at;type:timestamptz;autoUpdateTime"`
+}
+
+func (PolicyTemplate) TableName() string {
+	return "policy_templates"
+}
+
+// PolicyTemplateEntry is a single BAP decision within a template. An empty
+// Domain applies the decision to every domain.
+type PolicyTemplateEntry struct {
+	TemplateName string         `gorm:"primaryKey;column:template_name;type:text"`
+	Domain       string         `gorm:"primaryKey;column:domain;type:text"`
+	BapID        string         `gorm:"primaryKey;column:bap_id;type:text"`
+	Decision     AccessDecision `gorm:"column:decision;type:text"`
+	Reason       *string        `gorm:"column:reason;type:text"`
+}
+
+func (PolicyTemplateEntry) TableName() string {
+	return "policy_template_entries"
+}
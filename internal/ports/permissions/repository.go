@@ -4,5 +4,11 @@ type PermissionsRepository interface {
 	UpsertBaps(baps map[string]Bap) error
 	UpsertBapAccessPolicies(policies []BapAccessPolicy) error
 	FindBapByID(bapID string) (*Bap, error)
-	QueryBapAccessPolicies(bapID, domain, registryEnv string, sellerIDs []string) ([]BapAccessPolicy, error)
+	QueryBapAccessPolicies(bapID, domain, registryEnv string, sellerIDs, providerIDs []string) ([]BapAccessPolicy, error)
+	InsertBapAccessPoliciesIfAbsent(policies []BapAccessPolicy) (int64, error)
+	SavePolicyTemplate(template *PolicyTemplate) error
+	GetPolicyTemplates() ([]PolicyTemplate, error)
+	GetPolicyTemplate(name string) (*PolicyTemplate, error)
+	DeletePolicyTemplate(name string) error
+	GetAutoApplyPolicyTemplates(registryEnv string) ([]PolicyTemplate, error)
 }
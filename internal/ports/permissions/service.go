@@ -5,12 +5,37 @@ import (
 	"gorm.io/gorm/clause"
 )
 
+const (
+	// DefaultBatchSize is the number of rows written per statement when no
+	// batch size is configured.
+	DefaultBatchSize = 1000
+
+	// maxBindParams is the PostgreSQL limit on bind parameters per statement.
+	maxBindParams = 65535
+	// policyColumns is the number of columns bound per row of BAP access policies.
+	policyColumns = 11
+)
+
 type GormRepository struct {
-	db *gorm.DB
+	db        *gorm.DB
+	batchSize int
 }
 
 func NewGormRepository(db *gorm.DB) *GormRepository {
-	return &GormRepository{db: db}
+	return &GormRepository{db: db, batchSize: DefaultBatchSize}
+}
+
+// WithBatchSize sets how many rows each bulk write sends per statement. Sizes
+// that would exceed the PostgreSQL bind parameter limit are capped.
+func (r *GormRepository) WithBatchSize(size int) *GormRepository {
+	if size <= 0 {
+		size = DefaultBatchSize
+	}
+	if size > maxBindParams/policyColumns {
+		size = maxBindParams / policyColumns
+	}
+	r.batchSize = size
+	return r
 }
 
 func (r *GormRepository) UpsertBaps(baps map[string]Bap) error {
@@ -26,7 +51,7 @@ func (r *GormRepository) UpsertBaps(baps map[string]Bap) error {
 
 func (r *GormRepository) UpsertBapAccessPolicies(policies []BapAccessPolicy) error {
 	return r.db.Clauses(clause.OnConflict{
-		Columns:   []clause.Column{{Name: "seller_id"}, {Name: "domain"}, {Name: "registry_env"}, {Name: "bap_id"}},
+		Columns:   []clause.Column{{Name: "seller_id"}, {Name: "domain"}, {Name: "registry_env"}, {Name: "bap_id"}, {Name: "provider_id"}},
 		DoUpdates: clause.AssignmentColumns([]string{"decision", "decision_source", "decided_at", "expires_at", "reason", "updated_at"}),
 	}).Create(&policies).Error
 }
@@ -38,10 +63,90 @@ func (r *GormRepository) FindBapByID(bapID string) (*Bap, error) {
 	return &bap, nil
 }
 
-func (r *GormRepository) QueryBapAccessPolicies(bapID, domain, registryEnv string, sellerIDs []string) ([]BapAccessPolicy, error) {
+// QueryBapAccessPolicies returns the seller-level policies for the sellers along
+// with any provider-level policies for the given provider IDs.
+func (r *GormRepository) QueryBapAccessPolicies(bapID, domain, registryEnv string, sellerIDs, providerIDs []string) ([]BapAccessPolicy, error) {
 	var policies []BapAccessPolicy
-	if err := r.db.Where("bap_id = ? AND domain = ? AND registry_env = ? AND seller_id IN ?", bapID, domain, registryEnv, sellerIDs).Find(&policies).Error; err != nil {
+	providerScope := append([]string{""}, providerIDs...)
+	if err := r.db.Where("bap_id = ? AND domain = ? AND registry_env = ? AND seller_id IN ? AND provider_id IN ?", bapID, domain, registryEnv, sellerIDs, providerScope).Find(&policies).Error; err != nil {
 		return nil, err
 	}
 	return policies, nil
 }
+
+// InsertBapAccessPoliciesIfAbsent inserts the policies without touching any
+// decision that already exists, returning the number of rows inserted. Rows
+// are written in batches so a full-domain sync stays within the bind
+// parameter limit.
+func (r *GormRepository) InsertBapAccessPoliciesIfAbsent(policies []BapAccessPolicy) (int64, error) {
+	if len(policies) == 0 {
+		return 0, nil
+	}
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(&policies, r.batchSize)
+	return result.RowsAffected, result.Error
+}
+
+// SavePolicyTemplate creates or replaces a template together with its entries.
+func (r *GormRepository) SavePolicyTemplate(template *PolicyTemplate) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "name"}},
+			DoUpdates: clause.AssignmentColumns([]string{"description", "registry_env", "auto_apply", "updated_at"}),
+		}).Create(template).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("template_name = ?", template.Name).Delete(&PolicyTemplateEntry{}).Error; err != nil {
+			return err
+		}
+		for i := range template.Entries {
+			template.Entries[i].TemplateName = template.Name
+		}
+		if len(template.Entries) == 0 {
+			return nil
+		}
+		return tx.Create(&template.Entries).Error
+	})
+}
+
+func (r *GormRepository) GetPolicyTemplates() ([]PolicyTemplate, error) {
+	var templates []PolicyTemplate
+	if err := r.db.Preload("Entries").Order("name").Find(&templates).Error; err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+func (r *GormRepository) GetPolicyTemplate(name string) (*PolicyTemplate, error) {
+	var template PolicyTemplate
+	if err := r.db.Preload("Entries").First(&template, "name = ?", name).Error; err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+func (r *GormRepository) DeletePolicyTemplate(name string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("template_name = ?", name).Delete(&PolicyTemplateEntry{}).Error; err != nil {
+			return err
+		}
+		result := tx.Where("name = ?", name).Delete(&PolicyTemplate{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+func (r *GormRepository) GetAutoApplyPolicyTemplates(registryEnv string) ([]PolicyTemplate, error) {
+	var templates []PolicyTemplate
+	if err := r.db.Preload("Entries").
+		Where("auto_apply = ? AND (registry_env = ? OR registry_env = '')", true, registryEnv).
+		Order("name").
+		Find(&templates).Error; err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
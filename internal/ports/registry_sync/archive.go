@@ -0,0 +1,96 @@
+package registry_sync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+const (
+	ArchiveModeOff      = "off"
+	ArchiveModeDisk     = "disk"
+	ArchiveModePostgres = "postgres"
+)
+
+// NewLookupArchive returns the archive selected by mode, or nil when archiving
+// is off.
+func NewLookupArchive(mode, dir string, db *gorm.DB) (LookupArchive, error) {
+	switch strings.ToLower(mode) {
+	case "", ArchiveModeOff:
+		return nil, nil
+	case ArchiveModeDisk:
+		return NewFileLookupArchive(dir), nil
+	case ArchiveModePostgres:
+		return NewGormRepository(db), nil
+	default:
+		return nil, fmt.Errorf("unknown registry archive mode %q", mode)
+	}
+}
+
+// FileLookupArchive keeps archived responses as files laid out as
+// <dir>/<registry env>/<run id>/<domain>.json.gz.
+type FileLookupArchive struct {
+	dir string
+}
+
+func NewFileLookupArchive(dir string) *FileLookupArchive {
+	return &FileLookupArchive{dir: dir}
+}
+
+var domainFileReplacer = strings.NewReplacer(":", "_", "/", "_", `\`, "_")
+
+func (a *FileLookupArchive) path(registryEnv, runID, domain string) (string, error) {
+	for _, part := range []string{registryEnv, runID} {
+		if part == "" || part == "." || part == ".." || strings.ContainsAny(part, `/\`) {
+			return "", fmt.Errorf("invalid archive path element %q", part)
+		}
+	}
+	return filepath.Join(a.dir, registryEnv, runID, domainFileReplacer.Replace(domain)+".json.gz"), nil
+}
+
+func (a *FileLookupArchive) SaveLookupResponse(ctx context.Context, archive *RegistryLookupArchive) error {
+	path, err := a.path(archive.RegistryEnv, archive.RunID, archive.Domain)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	// Written under a temporary name so a replay never reads a partial file
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, archive.Body, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (a *FileLookupArchive) GetLookupResponse(ctx context.Context, registryEnv, runID, domain string) (*RegistryLookupArchive, error) {
+	path, err := a.path(registryEnv, runID, domain)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrLookupArchiveNotFound
+		}
+		return nil, err
+	}
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return &RegistryLookupArchive{
+		RunID:       runID,
+		Domain:      domain,
+		RegistryEnv: registryEnv,
+		Body:        body,
+		ArchivedAt:  info.ModTime(),
+	}, nil
+}
@@ -1,23 +1,166 @@
 package registry_sync
 
+import (
+	"time"
+
+	catalogPorts "adapter/internal/ports/catalog_sync"
+)
+
+// LookupCriteria narrows the registry lookup of a sync. Empty fields fall back
+// to the registry env's configured defaults.
+type LookupCriteria struct {
+	Country      string `json:"country,omitempty"`
+	City         string `json:"city,omitempty"`
+	Type         string `json:"type,omitempty"`
+	SubscriberID string `json:"subscriber_id,omitempty"`
+}
+
 // SyncRegistryRequest defines the request body for the /v1/internal/registry-sync API
 type SyncRegistryRequest struct {
-	RegistryEnv string   `json:"registry_env"`
-	Domains     []string `json:"domains"`
+	RegistryEnv   string        `json:"registry_env"`
+	Domains       []string      `json:"domains"`
+	TriggerSource TriggerSource `json:"-"`
+	// ReplayRunID reconciles from the responses archived by that run instead
+	// of calling the registry. A replay only reports what it would change.
+	ReplayRunID string `json:"replay_run_id,omitempty"`
+	LookupCriteria
 }
 
 // DomainSyncSummary provides a summary of the sync operation for a single domain
 type DomainSyncSummary struct {
-	Domain                 string `json:"domain"`
-	NewSellers             int    `json:"new_sellers"`
-	UpdatedSellers         int    `json:"updated_sellers"`
-	DeactivatedSellers     int    `json:"deactivated_sellers"`
-	TotalSellersInRegistry int    `json:"total_sellers_in_registry"`
-}
-
-// SyncRegistryResponse defines the response body for the /v1/internal/registry-sync API
-type SyncRegistryResponse struct {
-	RegistryEnv string              `json:"registry_env"`
-	Domains     []DomainSyncSummary `json:"domains"`
-	RunAt       string              `json:"run_at"`
+	Domain                  string `json:"domain"`
+	NewSellers              int    `json:"new_sellers"`
+	UpdatedSellers          int    `json:"updated_sellers"`
+	ReactivatedSellers      int    `json:"reactivated_sellers"`
+	UnchangedSellers        int    `json:"unchanged_sellers"`
+	DeactivatedSellers      int    `json:"deactivated_sellers"`
+	TotalSellersInRegistry  int    `json:"total_sellers_in_registry"`
+	TemplatePoliciesApplied int    `json:"template_policies_applied"`
+	InvalidValidityDates    int    `json:"invalid_validity_dates"`
+	PendingSellers          int    `json:"pending_sellers"`
+	InactiveByStatus        int    `json:"inactive_by_status"`
+	// MultiEntrySellers counts sellers listed by several registry entries,
+	// e.g. one per city or key
+	MultiEntrySellers int `json:"multi_entry_sellers"`
+	// StatusTransitions counts registry status changes, keyed "FROM->TO"
+	StatusTransitions map[string]int `json:"status_transitions,omitempty"`
+	// DeactivationsHeld is set when the sellers missing from the registry
+	// exceeded the mass-deactivation thresholds and were left active pending
+	// approval of DeactivationHoldID.
+	DeactivationsHeld  bool   `json:"deactivations_held"`
+	HeldDeactivations  int    `json:"held_deactivations,omitempty"`
+	DeactivationHoldID string `json:"deactivation_hold_id,omitempty"`
+}
+
+// DomainSyncError describes why a domain could not be synced
+type DomainSyncError struct {
+	Domain string `json:"domain"`
+	Error  string `json:"error"`
+}
+
+// SyncJobAcceptedResponse defines the response body for the /v1/internal/registry-sync API
+type SyncJobAcceptedResponse struct {
+	JobID            string         `json:"job_id"`
+	RegistryEnv      string         `json:"registry_env"`
+	Status           string         `json:"status"`
+	RequestedDomains []string       `json:"requested_domains"`
+	LookupCriteria   LookupCriteria `json:"lookup_criteria"`
+	ReplayOfRunID    string         `json:"replay_of_run_id,omitempty"`
+	QueuedAt         time.Time      `json:"queued_at"`
+}
+
+// RunningSyncJob identifies the job currently holding the sync lease on a domain
+type RunningSyncJob struct {
+	Domain         string    `json:"domain"`
+	JobID          string    `json:"job_id"`
+	LeaseExpiresAt time.Time `json:"lease_expires_at"`
+}
+
+// SyncJobProgress reports how far a registry sync job has got
+type SyncJobProgress struct {
+	DomainsTotal     int `json:"domains_total"`
+	DomainsDone      int `json:"domains_done"`
+	SellersProcessed int `json:"sellers_processed"`
+}
+
+// SyncRunResponse describes a recorded registry sync run
+type SyncRunResponse struct {
+	RunID             string              `json:"run_id"`
+	RegistryEnv       string              `json:"registry_env"`
+	TriggerSource     string              `json:"trigger_source"`
+	Status            string              `json:"status"`
+	RequestedDomains  []string            `json:"requested_domains"`
+	LookupCriteria    LookupCriteria      `json:"lookup_criteria"`
+	Progress          SyncJobProgress     `json:"progress"`
+	CancelRequested   bool                `json:"cancel_requested"`
+	ReplayOfRunID     *string             `json:"replay_of_run_id,omitempty"`
+	DeactivationsHeld bool                `json:"deactivations_held"`
+	StartedAt         time.Time           `json:"started_at"`
+	FinishedAt        *time.Time          `json:"finished_at"`
+	Error             *string             `json:"error,omitempty"`
+	Domains           []DomainSyncSummary `json:"domains,omitempty"`
+	Errors            []DomainSyncError   `json:"errors,omitempty"`
+}
+
+// SyncRunListResponse defines the response body for the registry sync run list API
+type SyncRunListResponse struct {
+	RegistryEnv string                `json:"registry_env,omitempty"`
+	Runs        []SyncRunResponse     `json:"runs"`
+	Page        catalogPorts.PageInfo `json:"page"`
+}
+
+// DomainLastSyncResponse reports the latest attempt and latest success for a domain
+type DomainLastSyncResponse struct {
+	Domain           string     `json:"domain"`
+	LastRunID        string     `json:"last_run_id"`
+	LastStatus       string     `json:"last_status"`
+	LastRunAt        time.Time  `json:"last_run_at"`
+	LastError        *string    `json:"last_error,omitempty"`
+	LastSuccessRunID *string    `json:"last_success_run_id"`
+	LastSuccessAt    *time.Time `json:"last_success_at"`
+}
+
+// SubscriberKeyResponse describes one published key of a subscriber
+type SubscriberKeyResponse struct {
+	UkID             string     `json:"ukId"`
+	SigningPublicKey string     `json:"signing_public_key"`
+	EncrPublicKey    string     `json:"encr_public_key"`
+	Lifecycle        string     `json:"lifecycle"`
+	ValidFrom        *time.Time `json:"valid_from"`
+	ValidUntil       *time.Time `json:"valid_until"`
+	Current          bool       `json:"current"`
+	FirstSeenAt      time.Time  `json:"first_seen_at"`
+	LastSeenAt       time.Time  `json:"last_seen_at"`
+	RotatedAt        *time.Time `json:"rotated_at,omitempty"`
+}
+
+// SubscriberKeysResponse defines the response body for the subscriber keys API
+type SubscriberKeysResponse struct {
+	SubscriberID string                  `json:"subscriber_id"`
+	RegistryEnv  string                  `json:"registry_env"`
+	Keys         []SubscriberKeyResponse `json:"keys"`
+}
+
+// HeldDeactivationResponse describes deactivations withheld by a sync
+type HeldDeactivationResponse struct {
+	HoldID        string     `json:"hold_id"`
+	RunID         string     `json:"run_id"`
+	RegistryEnv   string     `json:"registry_env"`
+	Domain        string     `json:"domain"`
+	Status        string     `json:"status"`
+	Reason        string     `json:"reason"`
+	SellerCount   int        `json:"seller_count"`
+	ActiveSellers int        `json:"active_sellers"`
+	SellerIDs     []string   `json:"seller_ids"`
+	Deactivated   int        `json:"deactivated"`
+	CreatedAt     time.Time  `json:"created_at"`
+	ResolvedAt    *time.Time `json:"resolved_at,omitempty"`
+}
+
+// HeldDeactivationListResponse defines the response body for the held deactivations list API
+type HeldDeactivationListResponse struct {
+	RegistryEnv string                     `json:"registry_env,omitempty"`
+	Status      string                     `json:"status,omitempty"`
+	Holds       []HeldDeactivationResponse `json:"holds"`
+	Page        catalogPorts.PageInfo      `json:"page"`
 }
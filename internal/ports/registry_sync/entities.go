@@ -0,0 +1,160 @@
+package registry_sync
+
+import "time"
+
+type TriggerSource string
+
+const (
+	TriggerSourceAPI  TriggerSource = "API"
+	TriggerSourceCron TriggerSource = "CRON"
+)
+
+type SyncRunStatus string
+
+const (
+	SyncRunStatusQueued    SyncRunStatus = "QUEUED"
+	SyncRunStatusRunning   SyncRunStatus = "RUNNING"
+	SyncRunStatusCompleted SyncRunStatus = "COMPLETED"
+	SyncRunStatusPartial   SyncRunStatus = "PARTIAL"
+	SyncRunStatusFailed    SyncRunStatus = "FAILED"
+	SyncRunStatusCancelled SyncRunStatus = "CANCELLED"
+)
+
+// IsFinished reports whether a run has reached a terminal status.
+func (s SyncRunStatus) IsFinished() bool {
+	return s != SyncRunStatusQueued && s != SyncRunStatusRunning
+}
+
+type DeactivationHoldStatus string
+
+const (
+	DeactivationHoldStatusHeld       DeactivationHoldStatus = "HELD"
+	DeactivationHoldStatusApproved   DeactivationHoldStatus = "APPROVED"
+	DeactivationHoldStatusDiscarded  DeactivationHoldStatus = "DISCARDED"
+	DeactivationHoldStatusSuperseded DeactivationHoldStatus = "SUPERSEDED"
+)
+
+type DomainSyncStatus string
+
+const (
+	DomainSyncStatusSucceeded DomainSyncStatus = "SUCCEEDED"
+	DomainSyncStatusFailed    DomainSyncStatus = "FAILED"
+)
+
+// RegistrySyncRun records a single registry sync job. The run ID doubles as the
+// job ID returned by the internal sync endpoint, and the progress counters are
+// updated as each domain finishes so the job can be polled.
+type RegistrySyncRun struct {
+	RunID            string                  `gorm:"primaryKey;column:run_id;type:text"`
+	RegistryEnv      string                  `gorm:"column:registry_env;type:text;index:idx_registry_sync_runs_env_started,priority:1"`
+	TriggerSource    TriggerSource           `gorm:"column:trigger_source;type:text"`
+	Status           SyncRunStatus           `gorm:"column:status;type:text"`
+	RequestedDomains string                  `gorm:"column:requested_domains;type:jsonb"`
+	LookupCriteria   string                  `gorm:"column:lookup_criteria;type:jsonb;default:'{}'"`
+	DomainsTotal     int                     `gorm:"column:domains_total;not null;default:0"`
+	DomainsDone      int                     `gorm:"column:domains_done;not null;default:0"`
+	SellersProcessed int                     `gorm:"column:sellers_processed;not null;default:0"`
+	CancelRequested  bool                    `gorm:"column:cancel_requested;not null;default:false"`
+	ReplayOfRunID    *string                 `gorm:"column:replay_of_run_id;type:text"`
+	Error            *string                 `gorm:"column:error;type:text"`
+	StartedAt        time.Time               `gorm:"column:started_at;type:timestamptz;index:idx_registry_sync_runs_env_started,priority:2,sort:desc"`
+	FinishedAt       *time.Time              `gorm:"column:finished_at;type:timestamptz"`
+	Domains          []RegistrySyncRunDomain `gorm:"foreignKey:RunID;references:RunID;constraint:OnDelete:CASCADE"`
+}
+
+func (RegistrySyncRun) TableName() string {
+	return "registry_sync_runs"
+}
+
+// RegistrySyncRunDomain records the outcome of one domain within a sync run.
+// Summary holds the DomainSyncSummary as JSON.
+type RegistrySyncRunDomain struct {
+	RunID       string           `gorm:"primaryKey;column:run_id;type:text"`
+	Domain      string           `gorm:"primaryKey;column:domain;type:text;index:idx_registry_sync_run_domains_lookup,priority:2"`
+	RegistryEnv string           `gorm:"column:registry_env;type:text;index:idx_registry_sync_run_domains_lookup,priority:1"`
+	Status      DomainSyncStatus `gorm:"column:status;type:text"`
+	Summary     *string          `gorm:"column:summary;type:jsonb"`
+	Error       *string          `gorm:"column:error;type:text"`
+	StartedAt   time.Time        `gorm:"column:started_at;type:timestamptz"`
+	FinishedAt  time.Time        `gorm:"column:finished_at;type:timestamptz;index:idx_registry_sync_run_domains_lookup,priority:3,sort:desc"`
+}
+
+func (RegistrySyncRunDomain) TableName() string {
+	return "registry_sync_run_domains"
+}
+
+// RegistrySyncLock is a lease on syncing one domain of a registry env. Holder
+// is the ID of the job holding the lease; a lease past ExpiresAt may be taken
+// over by another job.
+type RegistrySyncLock struct {
+	RegistryEnv string    `gorm:"primaryKey;column:registry_env;type:text"`
+	Domain      string    `gorm:"primaryKey;column:domain;type:text"`
+	Holder      string    `gorm:"column:holder;type:text;not null"`
+	AcquiredAt  time.Time `gorm:"column:acquired_at;type:timestamptz"`
+	ExpiresAt   time.Time `gorm:"column:expires_at;type:timestamptz"`
+}
+
+func (RegistrySyncLock) TableName() string {
+	return "registry_sync_locks"
+}
+
+// SubscriberKey is a signing and encryption key pair a subscriber published in
+// the registry under a unique key ID. Keys are kept after rotation; RotatedAt
+// is set once a sync no longer sees the key for the subscriber. Lifecycle is
+// the seller lifecycle the subscriber's registry status maps to when the key
+// was last seen; only keys of active subscribers are trusted.
+type SubscriberKey struct {
+	RegistryEnv      string     `gorm:"primaryKey;column:registry_env;type:text"`
+	SubscriberID     string     `gorm:"primaryKey;column:subscriber_id;type:text"`
+	UkID             string     `gorm:"primaryKey;column:uk_id;type:text"`
+	SigningPublicKey string     `gorm:"column:signing_public_key;type:text"`
+	EncrPublicKey    string     `gorm:"column:encr_public_key;type:text"`
+	Lifecycle        string     `gorm:"column:lifecycle;type:text"`
+	ValidFrom        *time.Time `gorm:"column:valid_from;type:timestamptz"`
+	ValidUntil       *time.Time `gorm:"column:valid_until;type:timestamptz"`
+	FirstSeenAt      time.Time  `gorm:"column:first_seen_at;type:timestamptz"`
+	LastSeenAt       time.Time  `gorm:"column:last_seen_at;type:timestamptz"`
+	RotatedAt        *time.Time `gorm:"column:rotated_at;type:timestamptz"`
+}
+
+func (SubscriberKey) TableName() string {
+	return "subscriber_keys"
+}
+
+// RegistryLookupArchive is the raw registry lookup response a run received for
+// one domain, gzip-compressed, kept so the run can be replayed offline.
+type RegistryLookupArchive struct {
+	RunID       string    `gorm:"primaryKey;column:run_id;type:text"`
+	Domain      string    `gorm:"primaryKey;column:domain;type:text"`
+	RegistryEnv string    `gorm:"column:registry_env;type:text"`
+	Body        []byte    `gorm:"column:body;type:bytea"`
+	Size        int       `gorm:"column:size"`
+	ArchivedAt  time.Time `gorm:"column:archived_at;type:timestamptz"`
+}
+
+func (RegistryLookupArchive) TableName() string {
+	return "registry_lookup_archives"
+}
+
+// HeldDeactivation is a set of seller deactivations a sync withheld because
+// they exceeded the mass-deactivation thresholds. SellerIDs holds the sellers
+// as a JSON array. A hold stays HELD until it is approved or discarded, or
+// until a later sync of the domain supersedes it.
+type HeldDeactivation struct {
+	HoldID        string                 `gorm:"primaryKey;column:hold_id;type:text"`
+	RunID         string                 `gorm:"column:run_id;type:text"`
+	RegistryEnv   string                 `gorm:"column:registry_env;type:text;index:idx_held_deactivations_lookup,priority:1"`
+	Domain        string                 `gorm:"column:domain;type:text;index:idx_held_deactivations_lookup,priority:2"`
+	Status        DeactivationHoldStatus `gorm:"column:status;type:text;index:idx_held_deactivations_lookup,priority:3"`
+	SellerIDs     string                 `gorm:"column:seller_ids;type:jsonb"`
+	SellerCount   int                    `gorm:"column:seller_count"`
+	ActiveSellers int                    `gorm:"column:active_sellers"`
+	Reason        string                 `gorm:"column:reason;type:text"`
+	Deactivated   int                    `gorm:"column:deactivated;not null;default:0"`
+	CreatedAt     time.Time              `gorm:"column:created_at;type:timestamptz"`
+	ResolvedAt    *time.Time             `gorm:"column:resolved_at;type:timestamptz"`
+}
+
+func (HeldDeactivation) TableName() string {
+	return "held_deactivations"
+}
@@ -0,0 +1,52 @@
+package registry_sync
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrLookupArchiveNotFound is returned when no response was archived for the
+// requested run and domain.
+var ErrLookupArchiveNotFound = errors.New("registry lookup response not archived")
+
+type SyncRunRepository interface {
+	CreateSyncRun(ctx context.Context, run *RegistrySyncRun) error
+	MarkSyncRunRunning(ctx context.Context, runID string) error
+	SaveSyncRunDomain(ctx context.Context, runDomain *RegistrySyncRunDomain) error
+	IncrementSyncRunProgress(ctx context.Context, runID string, sellersProcessed int) error
+	RequestSyncRunCancel(ctx context.Context, runID string) (bool, error)
+	IsSyncRunCancelRequested(ctx context.Context, runID string) (bool, error)
+	FinishSyncRun(ctx context.Context, runID string, status SyncRunStatus, finishedAt time.Time, runErr *string) error
+	GetSyncRuns(registryEnv string, limit, offset int) ([]RegistrySyncRun, error)
+	GetSyncRun(runID string) (*RegistrySyncRun, error)
+	GetLatestDomainRuns(registryEnv string, status DomainSyncStatus) ([]RegistrySyncRunDomain, error)
+}
+
+type SyncLockRepository interface {
+	AcquireSyncLocks(ctx context.Context, registryEnv string, domains []string, holder string, ttl time.Duration) ([]RegistrySyncLock, error)
+	RenewSyncLocks(ctx context.Context, registryEnv string, holder string, ttl time.Duration) (int64, error)
+	ReleaseSyncLocks(ctx context.Context, registryEnv string, holder string) error
+}
+
+type SubscriberKeyRepository interface {
+	UpsertSubscriberKeys(ctx context.Context, keys []SubscriberKey) error
+	MarkSubscriberKeysRotated(ctx context.Context, registryEnv string, subscriberIDs []string, seenSince time.Time) error
+	GetSubscriberKeys(registryEnv, subscriberID string) ([]SubscriberKey, error)
+	GetSubscriberKey(ctx context.Context, registryEnv, subscriberID, ukID string) (*SubscriberKey, error)
+}
+
+// LookupArchive stores raw registry lookup responses per run and domain.
+type LookupArchive interface {
+	SaveLookupResponse(ctx context.Context, archive *RegistryLookupArchive) error
+	GetLookupResponse(ctx context.Context, registryEnv, runID, domain string) (*RegistryLookupArchive, error)
+}
+
+type DeactivationHoldRepository interface {
+	CreateDeactivationHold(ctx context.Context, hold *HeldDeactivation) error
+	SupersedeDeactivationHolds(ctx context.Context, registryEnv, domain string) error
+	SupersedeExpiryDeactivationHolds(ctx context.Context, registryEnv, domain string) error
+	ResolveDeactivationHold(ctx context.Context, holdID string, from, to DeactivationHoldStatus, deactivated int) (bool, error)
+	GetDeactivationHolds(registryEnv string, status DeactivationHoldStatus, limit, offset int) ([]HeldDeactivation, error)
+	GetDeactivationHold(holdID string) (*HeldDeactivation, error)
+}
@@ -0,0 +1,353 @@
+package registry_sync
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// errSyncLocksHeld rolls back a partial lock acquisition.
+var errSyncLocksHeld = errors.New("registry sync locks held")
+
+const (
+	sqlStateDeadlockDetected     = "40P01"
+	sqlStateSerializationFailure = "40001"
+
+	acquireSyncLocksAttempts = 2
+)
+
+type GormRepository struct {
+	db *gorm.DB
+}
+
+func NewGormRepository(db *gorm.DB) *GormRepository {
+	return &GormRepository{db: db}
+}
+
+func (r *GormRepository) CreateSyncRun(ctx context.Context, run *RegistrySyncRun) error {
+	return r.db.WithContext(ctx).Omit("Domains").Create(run).Error
+}
+
+// MarkSyncRunRunning moves a queued run to RUNNING. Runs that were cancelled
+// before a worker picked them up are left untouched.
+func (r *GormRepository) MarkSyncRunRunning(ctx context.Context, runID string) error {
+	return r.db.WithContext(ctx).Model(&RegistrySyncRun{}).
+		Where("run_id = ? AND status = ?", runID, SyncRunStatusQueued).
+		Update("status", SyncRunStatusRunning).Error
+}
+
+func (r *GormRepository) SaveSyncRunDomain(ctx context.Context, runDomain *RegistrySyncRunDomain) error {
+	return r.db.WithContext(ctx).Save(runDomain).Error
+}
+
+func (r *GormRepository) IncrementSyncRunProgress(ctx context.Context, runID string, sellersProcessed int) error {
+	return r.db.WithContext(ctx).Model(&RegistrySyncRun{}).Where("run_id = ?", runID).Updates(map[string]interface{}{
+		"domains_done":      gorm.Expr("domains_done + 1"),
+		"sellers_processed": gorm.Expr("sellers_processed + ?", sellersProcessed),
+	}).Error
+}
+
+// RequestSyncRunCancel flags a queued or running run for cancellation. It
+// returns false when the run has already finished.
+func (r *GormRepository) RequestSyncRunCancel(ctx context.Context, runID string) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&RegistrySyncRun{}).
+		Where("run_id = ? AND status IN ?", runID, []SyncRunStatus{SyncRunStatusQueued, SyncRunStatusRunning}).
+		Update("cancel_requested", true)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *GormRepository) IsSyncRunCancelRequested(ctx context.Context, runID string) (bool, error) {
+	var run RegistrySyncRun
+	if err := r.db.WithContext(ctx).Select("cancel_requested").First(&run, "run_id = ?", runID).Error; err != nil {
+		return false, err
+	}
+	return run.CancelRequested, nil
+}
+
+func (r *GormRepository) FinishSyncRun(ctx context.Context, runID string, status SyncRunStatus, finishedAt time.Time, runErr *string) error {
+	return r.db.WithContext(ctx).Model(&RegistrySyncRun{}).Where("run_id = ?", runID).Updates(map[string]interface{}{
+		"status":      status,
+		"finished_at": finishedAt,
+		"error":       runErr,
+	}).Error
+}
+
+func (r *GormRepository) GetSyncRuns(registryEnv string, limit, offset int) ([]RegistrySyncRun, error) {
+	var runs []RegistrySyncRun
+	query := r.db.Model(&RegistrySyncRun{})
+	if registryEnv != "" {
+		query = query.Where("registry_env = ?", registryEnv)
+	}
+	if err := query.Order("started_at DESC").Limit(limit + 1).Offset(offset).Find(&runs).Error; err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
+
+func (r *GormRepository) GetSyncRun(runID string) (*RegistrySyncRun, error) {
+	var run RegistrySyncRun
+	if err := r.db.Preload("Domains", func(db *gorm.DB) *gorm.DB {
+		return db.Order("domain")
+	}).First(&run, "run_id = ?", runID).Error; err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// GetLatestDomainRuns returns the most recent run of every domain in the env,
+// optionally restricted to runs that ended with the given status.
+func (r *GormRepository) GetLatestDomainRuns(registryEnv string, status DomainSyncStatus) ([]RegistrySyncRunDomain, error) {
+	var runDomains []RegistrySyncRunDomain
+	query := r.db.Select("DISTINCT ON (domain) *").Where("registry_env = ?", registryEnv)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Order("domain, finished_at DESC").Find(&runDomains).Error; err != nil {
+		return nil, err
+	}
+	return runDomains, nil
+}
+
+// AcquireSyncLocks takes the lease on every domain for holder, or none of
+// them. Leases that have expired, or that holder already owns, are taken over.
+// When any domain is held by someone else, the conflicting locks are returned
+// and nothing is acquired. Domains are locked in sorted order so overlapping
+// acquisitions cannot deadlock; should one still lose a deadlock or
+// serialization conflict to a concurrent acquisition, it is reported as a
+// conflict with the winner's locks.
+func (r *GormRepository) AcquireSyncLocks(ctx context.Context, registryEnv string, domains []string, holder string, ttl time.Duration) ([]RegistrySyncLock, error) {
+	domains = sortedUnique(domains)
+	var err error
+	for attempt := 0; attempt < acquireSyncLocksAttempts; attempt++ {
+		var conflicts []RegistrySyncLock
+		conflicts, err = r.acquireSyncLocks(ctx, registryEnv, domains, holder, ttl)
+		if !isLockContention(err) {
+			return conflicts, err
+		}
+		// The winner may not have committed yet, in which case the next
+		// attempt waits for it and then sees its locks
+		var held []RegistrySyncLock
+		if err := r.db.WithContext(ctx).Where("registry_env = ? AND domain IN ? AND holder <> ? AND expires_at >= NOW()", registryEnv, domains, holder).Order("domain").Find(&held).Error; err != nil {
+			return nil, err
+		}
+		if len(held) > 0 {
+			return held, nil
+		}
+	}
+	return nil, err
+}
+
+func (r *GormRepository) acquireSyncLocks(ctx context.Context, registryEnv string, domains []string, holder string, ttl time.Duration) ([]RegistrySyncLock, error) {
+	var conflicts []RegistrySyncLock
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, domain := range domains {
+			result := tx.Exec(`
+				INSERT INTO registry_sync_locks (registry_env, domain, holder, acquired_at, expires_at)
+				VALUES (?, ?, ?, NOW(), NOW() + make_interval(secs => ?))
+				ON CONFLICT (registry_env, domain) DO UPDATE
+				SET holder = EXCLUDED.holder, acquired_at = EXCLUDED.acquired_at, expires_at = EXCLUDED.expires_at
+				WHERE registry_sync_locks.expires_at < NOW() OR registry_sync_locks.holder = EXCLUDED.holder`,
+				registryEnv, domain, holder, ttl.Seconds())
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				var lock RegistrySyncLock
+				if err := tx.First(&lock, "registry_env = ? AND domain = ?", registryEnv, domain).Error; err != nil {
+					return err
+				}
+				conflicts = append(conflicts, lock)
+			}
+		}
+		if len(conflicts) > 0 {
+			return errSyncLocksHeld
+		}
+		return nil
+	})
+	if err == errSyncLocksHeld {
+		return conflicts, nil
+	}
+	return nil, err
+}
+
+// isLockContention reports whether err is a PostgreSQL deadlock or
+// serialization failure.
+func isLockContention(err error) bool {
+	var pgErr interface{ SQLState() string }
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return pgErr.SQLState() == sqlStateDeadlockDetected || pgErr.SQLState() == sqlStateSerializationFailure
+}
+
+func sortedUnique(values []string) []string {
+	sorted := append([]string(nil), values...)
+	sort.Strings(sorted)
+	unique := sorted[:0]
+	for _, value := range sorted {
+		if len(unique) == 0 || value != unique[len(unique)-1] {
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
+
+// RenewSyncLocks extends every lease held by holder and returns how many were
+// renewed, so callers can tell when a lease has been lost.
+func (r *GormRepository) RenewSyncLocks(ctx context.Context, registryEnv string, holder string, ttl time.Duration) (int64, error) {
+	result := r.db.WithContext(ctx).Exec(`
+		UPDATE registry_sync_locks SET expires_at = NOW() + make_interval(secs => ?)
+		WHERE registry_env = ? AND holder = ?`,
+		ttl.Seconds(), registryEnv, holder)
+	return result.RowsAffected, result.Error
+}
+
+func (r *GormRepository) ReleaseSyncLocks(ctx context.Context, registryEnv string, holder string) error {
+	return r.db.WithContext(ctx).Where("registry_env = ? AND holder = ?", registryEnv, holder).Delete(&RegistrySyncLock{}).Error
+}
+
+// UpsertSubscriberKeys records the keys seen in a sync. A rotated key that
+// reappears is restored.
+func (r *GormRepository) UpsertSubscriberKeys(ctx context.Context, keys []SubscriberKey) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "registry_env"}, {Name: "subscriber_id"}, {Name: "uk_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"signing_public_key": gorm.Expr("EXCLUDED.signing_public_key"),
+			"encr_public_key":    gorm.Expr("EXCLUDED.encr_public_key"),
+			"lifecycle":          gorm.Expr("EXCLUDED.lifecycle"),
+			"valid_from":         gorm.Expr("EXCLUDED.valid_from"),
+			"valid_until":        gorm.Expr("EXCLUDED.valid_until"),
+			"last_seen_at":       gorm.Expr("EXCLUDED.last_seen_at"),
+			"rotated_at":         nil,
+		}),
+	}).CreateInBatches(&keys, 500).Error
+}
+
+// MarkSubscriberKeysRotated marks the keys of the given subscribers that have
+// not been seen since seenSince as rotated.
+func (r *GormRepository) MarkSubscriberKeysRotated(ctx context.Context, registryEnv string, subscriberIDs []string, seenSince time.Time) error {
+	now := time.Now()
+	for start := 0; start < len(subscriberIDs); start += 1000 {
+		batch := subscriberIDs[start:min(start+1000, len(subscriberIDs))]
+		if err := r.db.WithContext(ctx).Model(&SubscriberKey{}).
+			Where("registry_env = ? AND subscriber_id IN ? AND rotated_at IS NULL AND last_seen_at < ?", registryEnv, batch, seenSince).
+			Update("rotated_at", now).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetSubscriberKeys returns every key of a subscriber, current keys first.
+func (r *GormRepository) GetSubscriberKeys(registryEnv, subscriberID string) ([]SubscriberKey, error) {
+	var keys []SubscriberKey
+	if err := r.db.Where("registry_env = ? AND subscriber_id = ?", registryEnv, subscriberID).
+		Order("rotated_at DESC NULLS FIRST, last_seen_at DESC").Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (r *GormRepository) GetSubscriberKey(ctx context.Context, registryEnv, subscriberID, ukID string) (*SubscriberKey, error) {
+	var key SubscriberKey
+	if err := r.db.WithContext(ctx).First(&key, "registry_env = ? AND subscriber_id = ? AND uk_id = ?", registryEnv, subscriberID, ukID).Error; err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *GormRepository) SaveLookupResponse(ctx context.Context, archive *RegistryLookupArchive) error {
+	return r.db.WithContext(ctx).Save(archive).Error
+}
+
+func (r *GormRepository) GetLookupResponse(ctx context.Context, registryEnv, runID, domain string) (*RegistryLookupArchive, error) {
+	var archive RegistryLookupArchive
+	if err := r.db.WithContext(ctx).Where("run_id = ? AND domain = ? AND registry_env = ?", runID, domain, registryEnv).First(&archive).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrLookupArchiveNotFound
+		}
+		return nil, err
+	}
+	return &archive, nil
+}
+
+func (r *GormRepository) CreateDeactivationHold(ctx context.Context, hold *HeldDeactivation) error {
+	return r.db.WithContext(ctx).Create(hold).Error
+}
+
+// SupersedeDeactivationHolds retires the pending holds of a domain once a newer
+// sync has reconciled it.
+func (r *GormRepository) SupersedeDeactivationHolds(ctx context.Context, registryEnv, domain string) error {
+	return r.db.WithContext(ctx).Model(&HeldDeactivation{}).
+		Where("registry_env = ? AND domain = ? AND status = ?", registryEnv, domain, DeactivationHoldStatusHeld).
+		Updates(map[string]interface{}{
+			"status":      DeactivationHoldStatusSuperseded,
+			"resolved_at": time.Now(),
+		}).Error
+}
+
+// SupersedeExpiryDeactivationHolds supersedes the pending holds of a domain's
+// expired sellers, which are not tied to a sync run.
+func (r *GormRepository) SupersedeExpiryDeactivationHolds(ctx context.Context, registryEnv, domain string) error {
+	return r.db.WithContext(ctx).Model(&HeldDeactivation{}).
+		Where("registry_env = ? AND domain = ? AND status = ? AND run_id = ?", registryEnv, domain, DeactivationHoldStatusHeld, "").
+		Updates(map[string]interface{}{
+			"status":      DeactivationHoldStatusSuperseded,
+			"resolved_at": time.Now(),
+		}).Error
+}
+
+// ResolveDeactivationHold moves a hold from one status to another. It returns
+// false when the hold is no longer in the from status, so concurrent approvals
+// and discards cannot both succeed.
+func (r *GormRepository) ResolveDeactivationHold(ctx context.Context, holdID string, from, to DeactivationHoldStatus, deactivated int) (bool, error) {
+	updates := map[string]interface{}{
+		"status":      to,
+		"deactivated": deactivated,
+		"resolved_at": time.Now(),
+	}
+	if to == DeactivationHoldStatusHeld {
+		updates["resolved_at"] = nil
+	}
+	result := r.db.WithContext(ctx).Model(&HeldDeactivation{}).
+		Where("hold_id = ? AND status = ?", holdID, from).
+		Updates(updates)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *GormRepository) GetDeactivationHolds(registryEnv string, status DeactivationHoldStatus, limit, offset int) ([]HeldDeactivation, error) {
+	var holds []HeldDeactivation
+	query := r.db.Model(&HeldDeactivation{})
+	if registryEnv != "" {
+		query = query.Where("registry_env = ?", registryEnv)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Order("created_at DESC").Limit(limit + 1).Offset(offset).Find(&holds).Error; err != nil {
+		return nil, err
+	}
+	return holds, nil
+}
+
+func (r *GormRepository) GetDeactivationHold(holdID string) (*HeldDeactivation, error) {
+	var hold HeldDeactivation
+	if err := r.db.First(&hold, "hold_id = ?", holdID).Error; err != nil {
+		return nil, err
+	}
+	return &hold, nil
+}
@@ -0,0 +1,50 @@
+// Package authctx carries the callers verified by the ONDC signature
+// middleware and interceptors to the handlers, so handlers do not depend on
+// the middleware package.
+package authctx
+
+import "context"
+
+type subscriberKey struct{}
+
+type gatewayKey struct{}
+
+type registryEnvKey struct{}
+
+// WithSubscriberID returns a copy of ctx carrying the verified subscriber.
+func WithSubscriberID(ctx context.Context, subscriberID string) context.Context {
+	return context.WithValue(ctx, subscriberKey{}, subscriberID)
+}
+
+// SubscriberID returns the subscriber verified by the ONDC signature checks, or
+// an empty string if the request is not protected.
+func SubscriberID(ctx context.Context) string {
+	subscriberID, _ := ctx.Value(subscriberKey{}).(string)
+	return subscriberID
+}
+
+// WithGatewayID returns a copy of ctx carrying the verified gateway.
+func WithGatewayID(ctx context.Context, gatewayID string) context.Context {
+	return context.WithValue(ctx, gatewayKey{}, gatewayID)
+}
+
+// GatewayID returns the gateway verified by the ONDC signature checks, or an
+// empty string if the request did not come through a gateway.
+func GatewayID(ctx context.Context) string {
+	gatewayID, _ := ctx.Value(gatewayKey{}).(string)
+	return gatewayID
+}
+
+// WithRegistryEnv returns a copy of ctx carrying the registry env the verified
+// signatures were resolved in.
+func WithRegistryEnv(ctx context.Context, registryEnv string) context.Context {
+	return context.WithValue(ctx, registryEnvKey{}, registryEnv)
+}
+
+// RegistryEnv returns the registry env the verified signatures were resolved
+// in. It is only meaningful when SubscriberID is set; an empty value means the
+// signed request named no registry env.
+func RegistryEnv(ctx context.Context) string {
+	registryEnv, _ := ctx.Value(registryEnvKey{}).(string)
+	return registryEnv
+}
@@ -7,6 +7,30 @@ const (
 	ErrRequiredPermissionsFields    = "bap_id, domain, registry_env, and seller_ids are required"
 	ErrFailedToUpdatePermissions    = "Failed to update permissions"
 	ErrFailedToQueryPermissions     = "Failed to query permissions"
+	ErrBapIDMismatch                = "bap_id does not match the authenticated subscriber"
+	ErrRegistryEnvMismatch          = "registry_env does not match the authenticated registry env"
+
+	// ONDC Authentication Errors
+	ErrAuthorizationHeaderMissing   = "signature header is required"
+	ErrInvalidAuthorizationHeader   = "signature header is not a valid ONDC signature"
+	ErrSignatureExpired             = "signature is not within its created/expires window"
+	ErrSigningKeyNotFound           = "signing key could not be resolved for the keyId"
+	ErrInvalidSignature             = "signature verification failed"
+	ErrInvalidDigest                = "Digest header does not match the BLAKE-512 digest of the body"
+
+	// Policy Template Errors
+	ErrPolicyTemplateFieldsRequired = "template name and a non-empty entries array are required"
+	ErrInvalidPolicyTemplateEntry   = "each template entry requires a bap_id and a decision of ALLOWED or DENIED"
+	ErrPolicyTemplateNotFound       = "Policy template not found"
+	ErrFailedToSavePolicyTemplate   = "Failed to save policy template"
+	ErrFailedToGetPolicyTemplates   = "Failed to get policy templates"
+	ErrFailedToDeletePolicyTemplate = "Failed to delete policy template"
+
+	// Idempotency Errors
+	ErrIdempotencyKeyTooLong        = "Idempotency-Key header must not exceed 255 characters"
+	ErrIdempotencyKeyReused         = "Idempotency-Key has already been used with a different request"
+	ErrIdempotencyRequestInProgress = "A request with this Idempotency-Key is still being processed"
+	ErrIdempotencyCheckFailed       = "Failed to process Idempotency-Key"
 
 	// Catalog Sync Errors
 	ErrDomainRequired               = "domain query parameter is required"
@@ -16,7 +40,30 @@ const (
 	ErrGetPendingSellers            = "Failed to get pending catalog sync sellers"
 	ErrGetSyncStatus                = "Failed to get sync status"
 	ErrRecordNotFound               = "Record not found for the specified seller_id, domain, and registry_env"
+	ErrGetSellerChanges             = "Failed to get seller changes"
+	ErrGetExpiringSellers           = "Failed to get expiring sellers"
+	ErrInvalidExpiryWindow          = "within must be a positive duration, e.g. 72h"
+	ErrSellerLocationsNotFound      = "No registry locations found for the specified seller and registry_env"
+	ErrGetSellerLocations           = "Failed to get seller locations"
+	ErrGetCityCoverage              = "Failed to get city coverage"
 	
 	// Registry Sync Errors
 	ErrFailedToStartRegistrySync    = "Failed to start registry sync"
+	ErrUnknownRegistryEnv           = "registry_env is not a configured registry environment"
+	ErrRegistrySyncAlreadyRunning   = "Registry sync already running for one or more of the requested domains"
+	ErrSubscriberKeysNotFound       = "No keys found for the specified subscriber and registry_env"
+	ErrGetSubscriberKeys            = "Failed to get subscriber keys"
+	ErrGetSyncRuns                  = "Failed to get registry sync runs"
+	ErrSyncRunNotFound              = "Registry sync run not found"
+	ErrSyncJobNotFound              = "Registry sync job not found"
+	ErrSyncJobAlreadyFinished       = "Registry sync job has already finished"
+	ErrFailedToCancelSyncJob        = "Failed to cancel registry sync job"
+	ErrRegistryArchiveDisabled      = "Replay requires REGISTRY_ARCHIVE_MODE to be disk or postgres"
+	ErrReplayRunNotFound            = "Registry sync run to replay not found"
+	ErrReplayEnvMismatch            = "registry_env does not match the registry_env of the run to replay"
+	ErrGetHeldDeactivations         = "Failed to get held deactivations"
+	ErrHeldDeactivationNotFound     = "Held deactivation not found"
+	ErrHeldDeactivationResolved     = "Held deactivation has already been approved, discarded or superseded"
+	ErrResolveHeldDeactivation      = "Failed to resolve held deactivation"
+	ErrHeldDeactivationSyncRunning  = "Cannot approve held deactivations while a registry sync of the domain is running"
 )
@@ -0,0 +1,89 @@
+package crypto
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// AuthorizationHeader is a parsed ONDC signature header of the form
+// Signature keyId="{subscriber_id}|{unique_key_id}|{algorithm}",algorithm="ed25519",
+// created="...",expires="...",headers="(created) (expires) digest",signature="..."
+type AuthorizationHeader struct {
+	SubscriberID string
+	UniqueKeyID  string
+	Algorithm    string
+	Created      int
+	Expires      int
+	Headers      string
+	Signature    string
+}
+
+// ParseAuthorizationHeader parses an ONDC Authorization header value.
+func ParseAuthorizationHeader(header string) (*AuthorizationHeader, error) {
+	header = strings.TrimSpace(header)
+	if !strings.HasPrefix(header, "Signature ") {
+		return nil, fmt.Errorf("authorization header must use the Signature scheme")
+	}
+
+	params := make(map[string]string)
+	for _, part := range splitHeaderParams(strings.TrimPrefix(header, "Signature ")) {
+		key, value, found := strings.Cut(part, "=")
+		if !found {
+			return nil, fmt.Errorf("malformed authorization header parameter %q", part)
+		}
+		params[strings.TrimSpace(key)] = strings.Trim(strings.TrimSpace(value), `"`)
+	}
+
+	keyID := strings.Split(params["keyId"], "|")
+	if len(keyID) != 3 || keyID[0] == "" || keyID[1] == "" {
+		return nil, fmt.Errorf("keyId must be of the form subscriber_id|unique_key_id|algorithm")
+	}
+
+	created, err := strconv.Atoi(params["created"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid created parameter: %w", err)
+	}
+	expires, err := strconv.Atoi(params["expires"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid expires parameter: %w", err)
+	}
+	if params["signature"] == "" {
+		return nil, fmt.Errorf("signature parameter is required")
+	}
+
+	algorithm := params["algorithm"]
+	if algorithm == "" {
+		algorithm = keyID[2]
+	}
+
+	return &AuthorizationHeader{
+		SubscriberID: keyID[0],
+		UniqueKeyID:  keyID[1],
+		Algorithm:    algorithm,
+		Created:      created,
+		Expires:      expires,
+		Headers:      params["headers"],
+		Signature:    params["signature"],
+	}, nil
+}
+
+// splitHeaderParams splits on commas that are not inside quoted values.
+func splitHeaderParams(s string) []string {
+	var parts []string
+	inQuotes := false
+	start := 0
+	for i, r := range s {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+		case r == ',' && !inQuotes:
+			parts = append(parts, strings.TrimSpace(s[start:i]))
+			start = i + 1
+		}
+	}
+	if rest := strings.TrimSpace(s[start:]); rest != "" {
+		parts = append(parts, rest)
+	}
+	return parts
+}
@@ -73,6 +73,10 @@ func (c *ONDCCrypto) VerifyRequest(publicKeyStr string, payload []byte, created,
 	if err != nil {
 		return false, fmt.Errorf("error decoding public key: %w", err)
 	}
+	if len(publicKeyBytes) != ed25519.PublicKeySize {
+		// ed25519.Verify panics on keys of any other length
+		return false, fmt.Errorf("invalid public key length: got %d bytes, want %d", len(publicKeyBytes), ed25519.PublicKeySize)
+	}
 
 	// Decode signature
 	receivedSignature, err := base64.StdEncoding.DecodeString(signatureStr)
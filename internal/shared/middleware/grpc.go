@@ -0,0 +1,234 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"adapter/internal/shared/log"
+	"adapter/internal/shared/tracing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const requestIDMetadataKey = "x-request-id"
+
+// grpcHTTPStatus maps gRPC status codes onto the HTTP status codes used by the
+// request logs and spans, so both transports report outcomes the same way.
+var grpcHTTPStatus = map[codes.Code]int{
+	codes.OK:                 http.StatusOK,
+	codes.Canceled:           499,
+	codes.InvalidArgument:    http.StatusBadRequest,
+	codes.FailedPrecondition: http.StatusBadRequest,
+	codes.OutOfRange:         http.StatusBadRequest,
+	codes.Unauthenticated:    http.StatusUnauthorized,
+	codes.PermissionDenied:   http.StatusForbidden,
+	codes.NotFound:           http.StatusNotFound,
+	codes.AlreadyExists:      http.StatusConflict,
+	codes.Aborted:            http.StatusConflict,
+	codes.ResourceExhausted:  http.StatusTooManyRequests,
+	codes.Unimplemented:      http.StatusNotImplemented,
+	codes.Unavailable:        http.StatusServiceUnavailable,
+	codes.DeadlineExceeded:   http.StatusGatewayTimeout,
+}
+
+// metadataCarrier adapts incoming gRPC metadata to the OpenTelemetry propagator.
+type metadataCarrier metadata.MD
+
+func (m metadataCarrier) Get(key string) string {
+	values := metadata.MD(m).Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+func (m metadataCarrier) Set(key, value string) {
+	metadata.MD(m).Set(key, value)
+}
+
+func (m metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func convertGRPCRequest(ctx context.Context, fullMethod string) *http.Request {
+	req := &http.Request{
+		Method: http.MethodPost,
+		URL: &url.URL{
+			Scheme: "grpc",
+			Path:   fullMethod,
+		},
+		Header: make(http.Header),
+	}
+
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for key, values := range md {
+			for _, value := range values {
+				req.Header.Add(key, value)
+			}
+		}
+		if authority := md.Get(":authority"); len(authority) > 0 {
+			req.Host = authority[0]
+			req.URL.Host = authority[0]
+		}
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		req.RemoteAddr = p.Addr.String()
+	}
+
+	return req
+}
+
+// startGRPCRequest assigns a request ID and starts the server span, matching
+// what LoggingMiddleware and RequestIDMiddleware do for HTTP requests.
+func startGRPCRequest(ctx context.Context, fullMethod string) (context.Context, trace.Span, *http.Request) {
+	requestID := uuid.New().String()
+	ctx = context.WithValue(ctx, "request_id", requestID)
+
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		ctx = otel.GetTextMapPropagator().Extract(ctx, metadataCarrier(md))
+	}
+
+	httpReq := convertGRPCRequest(ctx, fullMethod)
+	ctx, span := tracing.StartHTTPSpan(ctx, httpReq, fullMethod)
+
+	span.SetAttributes(
+		attribute.String("request_id", requestID),
+		attribute.String("rpc.system", "grpc"),
+		attribute.String("rpc.method", fullMethod),
+	)
+
+	_ = grpc.SetHeader(ctx, metadata.Pairs(requestIDMetadataKey, requestID))
+
+	return ctx, span, httpReq
+}
+
+func finishGRPCRequest(ctx context.Context, span trace.Span, httpReq *http.Request, start time.Time, responseSize int, err error) {
+	code := status.Code(err)
+	statusCode, ok := grpcHTTPStatus[code]
+	if !ok {
+		statusCode = http.StatusInternalServerError
+	}
+
+	if err != nil {
+		tracing.RecordError(span, err, "Handler error")
+		log.ErrorWithStack(ctx, err, "Request handler error")
+	}
+
+	tracing.SetHTTPStatus(span, statusCode)
+	responseTime := time.Since(start)
+	span.SetAttributes(
+		attribute.String("rpc.grpc.status_code", code.String()),
+		attribute.Int("http.response_body_size", responseSize),
+		attribute.Float64("http.response_time_ms", float64(responseTime.Nanoseconds())/1e6),
+	)
+
+	log.RequestEnd(ctx, httpReq, statusCode, responseTime, responseSize)
+}
+
+// UnaryLoggingInterceptor is the gRPC counterpart of LoggingMiddleware.
+func UnaryLoggingInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, span, httpReq := startGRPCRequest(ctx, info.FullMethod)
+		defer span.End()
+
+		start := time.Now()
+		var requestBody []byte
+		if msg, ok := req.(proto.Message); ok {
+			requestBody, _ = protojson.Marshal(msg)
+		}
+		log.RequestStart(ctx, httpReq, requestBody)
+
+		resp, err := handler(ctx, req)
+
+		responseSize := 0
+		if msg, ok := resp.(proto.Message); ok && err == nil {
+			responseSize = proto.Size(msg)
+		}
+		finishGRPCRequest(ctx, span, httpReq, start, responseSize, err)
+
+		return resp, err
+	}
+}
+
+type loggingServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *loggingServerStream) Context() context.Context {
+	return s.ctx
+}
+
+// StreamLoggingInterceptor is the gRPC counterpart of LoggingMiddleware for
+// streaming calls; the whole stream is logged and traced as one request.
+func StreamLoggingInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, span, httpReq := startGRPCRequest(ss.Context(), info.FullMethod)
+		defer span.End()
+
+		start := time.Now()
+		log.RequestStart(ctx, httpReq, nil)
+
+		err := handler(srv, &loggingServerStream{ServerStream: ss, ctx: ctx})
+
+		finishGRPCRequest(ctx, span, httpReq, start, 0, err)
+
+		return err
+	}
+}
+
+// UnaryRecoveryInterceptor is the gRPC counterpart of RecoveryMiddleware.
+func UnaryRecoveryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = recoverGRPCPanic(ctx, info.FullMethod, r)
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+// StreamRecoveryInterceptor is the gRPC counterpart of RecoveryMiddleware for
+// streaming calls.
+func StreamRecoveryInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = recoverGRPCPanic(ss.Context(), info.FullMethod, r)
+			}
+		}()
+		return handler(srv, ss)
+	}
+}
+
+func recoverGRPCPanic(ctx context.Context, fullMethod string, r interface{}) error {
+	span := trace.SpanFromContext(ctx)
+	if !span.SpanContext().IsValid() {
+		ctx, span = tracing.StartSpan(ctx, "panic_recovery")
+		defer span.End()
+	}
+
+	log.PanicLog(ctx, convertGRPCRequest(ctx, fullMethod), r)
+	tracing.RecordError(span, fmt.Errorf("panic: %v", r), "Panic recovered")
+
+	return status.Error(codes.Internal, "Internal server error")
+}
@@ -0,0 +1,189 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	idempotencyPorts "adapter/internal/ports/idempotency"
+	"adapter/internal/shared/authctx"
+	"adapter/internal/shared/constants"
+	"adapter/internal/shared/log"
+	"adapter/internal/shared/utils"
+)
+
+const (
+	IdempotencyKeyHeader     = "Idempotency-Key"
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
+
+	defaultIdempotencyInProgressTTL = time.Minute
+)
+
+// IdempotencyConfig configures IdempotencyMiddleware.
+type IdempotencyConfig struct {
+	Repo idempotencyPorts.IdempotencyRepository
+	// TTL is how long a completed response is replayed
+	TTL time.Duration
+	// InProgressTTL is how long a claim may stay in progress before another
+	// request can take it over, e.g. after a crash
+	InProgressTTL time.Duration
+	// CallerHeader identifies the caller, e.g. the API key header. Requests
+	// without it are told apart by the ONDC signer or the client IP.
+	CallerHeader string
+}
+
+// IdempotencyMiddleware replays the stored response for requests that repeat an
+// Idempotency-Key, and rejects keys reused with a different request. Keys are
+// scoped to the caller, method and path. Requests without the header are
+// passed through untouched.
+func IdempotencyMiddleware(cfg IdempotencyConfig) fiber.Handler {
+	if cfg.InProgressTTL <= 0 {
+		cfg.InProgressTTL = defaultIdempotencyInProgressTTL
+	}
+	repo := cfg.Repo
+
+	var handler fiber.Handler
+	handler = func(c *fiber.Ctx) error {
+		clientKey := c.Get(IdempotencyKeyHeader)
+		if clientKey == "" {
+			return c.Next()
+		}
+		if len(clientKey) > maxIdempotencyKeyLength {
+			return c.Status(fiber.StatusBadRequest).JSON(utils.ApiResponse{
+				Success: false,
+				Message: constants.ErrIdempotencyKeyTooLong,
+			})
+		}
+
+		ctx := c.UserContext()
+		requestHash := fingerprintRequest(c)
+		caller := idempotencyCaller(c, cfg.CallerHeader)
+		key := scopeIdempotencyKey(caller, c.Method(), c.Path(), clientKey)
+		owner := uuid.New().String()
+		now := time.Now()
+
+		created, err := repo.CreateIdempotencyRecord(&idempotencyPorts.IdempotencyRecord{
+			Key:         key,
+			Caller:      caller,
+			Owner:       owner,
+			Method:      c.Method(),
+			Path:        c.Path(),
+			RequestHash: requestHash,
+			Status:      idempotencyPorts.IdempotencyStatusInProgress,
+			ExpiresAt:   now.Add(cfg.TTL),
+		})
+		if err != nil {
+			log.Error(ctx, err, "Failed to store idempotency key")
+			return idempotencyCheckFailed(c)
+		}
+
+		if !created {
+			record, err := repo.GetIdempotencyRecord(key)
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				log.Error(ctx, err, "Failed to load idempotency key")
+				return idempotencyCheckFailed(c)
+			}
+
+			// Expired keys and claims abandoned by a crashed request can be taken over.
+			if record == nil || now.After(record.ExpiresAt) ||
+				(record.Status == idempotencyPorts.IdempotencyStatusInProgress && now.Sub(record.CreatedAt) > cfg.InProgressTTL) {
+				// Only the claim that was seen is deleted, so a concurrent
+				// takeover is never undone
+				if record != nil {
+					if err := repo.DeleteIdempotencyRecord(key, record.Owner); err != nil {
+						log.Error(ctx, err, "Failed to delete stale idempotency key")
+						return idempotencyCheckFailed(c)
+					}
+				}
+				return handler(c)
+			}
+
+			if record.RequestHash != requestHash {
+				return c.Status(fiber.StatusUnprocessableEntity).JSON(utils.ApiResponse{
+					Success: false,
+					Message: constants.ErrIdempotencyKeyReused,
+				})
+			}
+
+			if record.Status != idempotencyPorts.IdempotencyStatusCompleted {
+				return c.Status(fiber.StatusConflict).JSON(utils.ApiResponse{
+					Success: false,
+					Message: constants.ErrIdempotencyRequestInProgress,
+				})
+			}
+
+			c.Set(IdempotentReplayedHeader, "true")
+			if record.ContentType != "" {
+				c.Set(fiber.HeaderContentType, record.ContentType)
+			}
+			return c.Status(record.ResponseStatus).Send(record.ResponseBody)
+		}
+
+		err = c.Next()
+
+		// Only keep responses the client should not retry; server errors free the key again.
+		statusCode := c.Response().StatusCode()
+		if err != nil || statusCode >= fiber.StatusInternalServerError {
+			if delErr := repo.ReleaseIdempotencyRecord(key, owner); delErr != nil {
+				log.Error(ctx, delErr, "Failed to release idempotency key")
+			}
+			return err
+		}
+
+		body := append([]byte(nil), c.Response().Body()...)
+		contentType := string(c.Response().Header.ContentType())
+		if err := repo.CompleteIdempotencyRecord(key, owner, statusCode, contentType, body); err != nil {
+			log.Error(ctx, err, "Failed to store idempotent response")
+		}
+
+		return nil
+	}
+	return handler
+}
+
+// idempotencyCaller identifies who sent a request: the verified ONDC signer,
+// else a hash of the caller header, else the client IP.
+func idempotencyCaller(c *fiber.Ctx, callerHeader string) string {
+	if subscriberID := authctx.SubscriberID(c.UserContext()); subscriberID != "" {
+		return "ondc:" + subscriberID
+	}
+	if callerHeader != "" {
+		if value := c.Get(callerHeader); value != "" {
+			sum := sha256.Sum256([]byte(value))
+			return "key:" + hex.EncodeToString(sum[:])
+		}
+	}
+	return "ip:" + c.IP()
+}
+
+func scopeIdempotencyKey(caller, method, path, clientKey string) string {
+	h := sha256.New()
+	for _, part := range []string{caller, method, path, clientKey} {
+		h.Write([]byte(part))
+		h.Write([]byte{'\n'})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func fingerprintRequest(c *fiber.Ctx) string {
+	h := sha256.New()
+	h.Write([]byte(c.Method()))
+	h.Write([]byte{'\n'})
+	h.Write([]byte(c.Path()))
+	h.Write([]byte{'\n'})
+	h.Write(c.Body())
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func idempotencyCheckFailed(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusInternalServerError).JSON(utils.ApiResponse{
+		Success: false,
+		Message: constants.ErrIdempotencyCheckFailed,
+	})
+}
@@ -0,0 +1,329 @@
+package middleware
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+
+	idempotencyPorts "adapter/internal/ports/idempotency"
+)
+
+// memoryIdempotency is an in-memory IdempotencyRepository.
+type memoryIdempotency struct {
+	mu      sync.Mutex
+	records map[string]idempotencyPorts.IdempotencyRecord
+}
+
+func newMemoryIdempotency() *memoryIdempotency {
+	return &memoryIdempotency{records: make(map[string]idempotencyPorts.IdempotencyRecord)}
+}
+
+func (r *memoryIdempotency) CreateIdempotencyRecord(record *idempotencyPorts.IdempotencyRecord) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.records[record.Key]; ok {
+		return false, nil
+	}
+	if record.CreatedAt.IsZero() {
+		record.CreatedAt = time.Now()
+	}
+	r.records[record.Key] = *record
+	return true, nil
+}
+
+func (r *memoryIdempotency) GetIdempotencyRecord(key string) (*idempotencyPorts.IdempotencyRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	record, ok := r.records[key]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &record, nil
+}
+
+func (r *memoryIdempotency) CompleteIdempotencyRecord(key, owner string, responseStatus int, contentType string, responseBody []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	record, ok := r.records[key]
+	if !ok || record.Owner != owner {
+		return nil
+	}
+	record.Status = idempotencyPorts.IdempotencyStatusCompleted
+	record.ResponseStatus = responseStatus
+	record.ContentType = contentType
+	record.ResponseBody = responseBody
+	r.records[key] = record
+	return nil
+}
+
+func (r *memoryIdempotency) DeleteIdempotencyRecord(key, owner string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if record, ok := r.records[key]; ok && record.Owner == owner {
+		delete(r.records, key)
+	}
+	return nil
+}
+
+func (r *memoryIdempotency) ReleaseIdempotencyRecord(key, owner string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if record, ok := r.records[key]; ok && record.Owner == owner && record.Status == idempotencyPorts.IdempotencyStatusInProgress {
+		delete(r.records, key)
+	}
+	return nil
+}
+
+func (r *memoryIdempotency) DeleteExpiredIdempotencyRecords(before time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var deleted int64
+	for key, record := range r.records {
+		if record.ExpiresAt.Before(before) {
+			delete(r.records, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// only returns the single stored record.
+func (r *memoryIdempotency) only(t *testing.T) (idempotencyPorts.IdempotencyRecord, bool) {
+	t.Helper()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.records) > 1 {
+		t.Fatalf("%d idempotency records stored, want at most 1", len(r.records))
+	}
+	for _, record := range r.records {
+		return record, true
+	}
+	return idempotencyPorts.IdempotencyRecord{}, false
+}
+
+func (r *memoryIdempotency) update(t *testing.T, change func(*idempotencyPorts.IdempotencyRecord)) {
+	t.Helper()
+	record, ok := r.only(t)
+	if !ok {
+		t.Fatal("no idempotency record to change")
+	}
+	change(&record)
+	r.mu.Lock()
+	r.records[record.Key] = record
+	r.mu.Unlock()
+}
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	const (
+		ttl           = time.Hour
+		inProgressTTL = time.Minute
+	)
+	tests := []struct {
+		name string
+		// first is sent and, after change is applied to its record, followed
+		// by second
+		first, second     string
+		firstCaller       string
+		change            func(*idempotencyPorts.IdempotencyRecord)
+		key               string
+		callerHeader      string
+		handlerStatus     int
+		wantStatus        int
+		wantHandlerCalls  int
+		wantReplayed      bool
+		wantRecord        bool
+		wantRecordStatus  idempotencyPorts.IdempotencyStatus
+		wantOwnerReplaced bool
+	}{
+		{
+			name:             "replays a completed response",
+			first:            `{"n":1}`,
+			second:           `{"n":1}`,
+			key:              "key-1",
+			wantStatus:       fiber.StatusCreated,
+			wantHandlerCalls: 1,
+			wantReplayed:     true,
+			wantRecord:       true,
+			wantRecordStatus: idempotencyPorts.IdempotencyStatusCompleted,
+		},
+		{
+			name:             "rejects a key reused with another body",
+			first:            `{"n":1}`,
+			second:           `{"n":2}`,
+			key:              "key-1",
+			wantStatus:       fiber.StatusUnprocessableEntity,
+			wantHandlerCalls: 1,
+			wantRecord:       true,
+			wantRecordStatus: idempotencyPorts.IdempotencyStatusCompleted,
+		},
+		{
+			name:   "rejects a repeat while the claim is in progress",
+			first:  `{"n":1}`,
+			second: `{"n":1}`,
+			change: func(r *idempotencyPorts.IdempotencyRecord) {
+				r.Status = idempotencyPorts.IdempotencyStatusInProgress
+			},
+			key:              "key-1",
+			wantStatus:       fiber.StatusConflict,
+			wantHandlerCalls: 1,
+			wantRecord:       true,
+			wantRecordStatus: idempotencyPorts.IdempotencyStatusInProgress,
+		},
+		{
+			name:   "takes over a claim in progress past its TTL",
+			first:  `{"n":1}`,
+			second: `{"n":1}`,
+			change: func(r *idempotencyPorts.IdempotencyRecord) {
+				r.Status = idempotencyPorts.IdempotencyStatusInProgress
+				r.CreatedAt = time.Now().Add(-2 * inProgressTTL)
+			},
+			key:               "key-1",
+			wantStatus:        fiber.StatusCreated,
+			wantHandlerCalls:  2,
+			wantRecord:        true,
+			wantRecordStatus:  idempotencyPorts.IdempotencyStatusCompleted,
+			wantOwnerReplaced: true,
+		},
+		{
+			name:   "takes over an expired key",
+			first:  `{"n":1}`,
+			second: `{"n":2}`,
+			change: func(r *idempotencyPorts.IdempotencyRecord) {
+				r.ExpiresAt = time.Now().Add(-time.Second)
+			},
+			key:               "key-1",
+			wantStatus:        fiber.StatusCreated,
+			wantHandlerCalls:  2,
+			wantRecord:        true,
+			wantRecordStatus:  idempotencyPorts.IdempotencyStatusCompleted,
+			wantOwnerReplaced: true,
+		},
+		{
+			name:             "frees the key after a server error",
+			first:            `{"n":1}`,
+			second:           `{"n":1}`,
+			key:              "key-1",
+			handlerStatus:    fiber.StatusServiceUnavailable,
+			wantStatus:       fiber.StatusServiceUnavailable,
+			wantHandlerCalls: 2,
+		},
+		{
+			name:             "scopes keys to the caller",
+			first:            `{"n":1}`,
+			firstCaller:      "other-api-key",
+			second:           `{"n":2}`,
+			key:              "key-1",
+			callerHeader:     "X-API-Key",
+			wantStatus:       fiber.StatusCreated,
+			wantHandlerCalls: 2,
+		},
+		{
+			name:             "passes requests without a key through",
+			second:           `{"n":1}`,
+			wantStatus:       fiber.StatusCreated,
+			wantHandlerCalls: 1,
+		},
+		{
+			name:       "rejects an overlong key",
+			second:     `{"n":1}`,
+			key:        strings.Repeat("k", maxIdempotencyKeyLength+1),
+			wantStatus: fiber.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newMemoryIdempotency()
+			handlerStatus := tt.handlerStatus
+			if handlerStatus == 0 {
+				handlerStatus = fiber.StatusCreated
+			}
+			calls := 0
+			app := fiber.New()
+			app.Post("/orders", IdempotencyMiddleware(IdempotencyConfig{
+				Repo:          repo,
+				TTL:           ttl,
+				InProgressTTL: inProgressTTL,
+				CallerHeader:  tt.callerHeader,
+			}), func(c *fiber.Ctx) error {
+				calls++
+				return c.Status(handlerStatus).SendString("order " + string(c.Body()))
+			})
+
+			send := func(body, caller string) (int, string, bool) {
+				t.Helper()
+				req := httptest.NewRequest(fiber.MethodPost, "/orders", strings.NewReader(body))
+				if tt.key != "" {
+					req.Header.Set(IdempotencyKeyHeader, tt.key)
+				}
+				if caller != "" {
+					req.Header.Set("X-API-Key", caller)
+				}
+				resp, err := app.Test(req)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer resp.Body.Close()
+				raw, err := io.ReadAll(resp.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return resp.StatusCode, string(raw), resp.Header.Get(IdempotentReplayedHeader) == "true"
+			}
+
+			var firstOwner string
+			if tt.first != "" {
+				send(tt.first, tt.firstCaller)
+				if record, ok := repo.only(t); ok {
+					firstOwner = record.Owner
+				}
+				if tt.change != nil {
+					repo.update(t, tt.change)
+				}
+			}
+
+			status, body, replayed := send(tt.second, "my-api-key")
+			if status != tt.wantStatus {
+				t.Errorf("status %d, want %d: %s", status, tt.wantStatus, body)
+			}
+			if calls != tt.wantHandlerCalls {
+				t.Errorf("handler called %d times, want %d", calls, tt.wantHandlerCalls)
+			}
+			if replayed != tt.wantReplayed {
+				t.Errorf("replayed=%v, want %v", replayed, tt.wantReplayed)
+			}
+			if tt.wantReplayed && body != "order "+tt.first {
+				t.Errorf("replayed %q, want the first response", body)
+			}
+
+			if tt.firstCaller != "" {
+				repo.mu.Lock()
+				n := len(repo.records)
+				repo.mu.Unlock()
+				if n != 2 {
+					t.Errorf("%d idempotency records stored, want one per caller", n)
+				}
+				return
+			}
+			record, ok := repo.only(t)
+			if ok != tt.wantRecord {
+				t.Fatalf("record stored=%v, want %v", ok, tt.wantRecord)
+			}
+			if !ok {
+				return
+			}
+			if record.Status != tt.wantRecordStatus {
+				t.Errorf("record is %s, want %s", record.Status, tt.wantRecordStatus)
+			}
+			if replaced := record.Owner != firstOwner; replaced != tt.wantOwnerReplaced {
+				t.Errorf("owner replaced=%v, want %v", replaced, tt.wantOwnerReplaced)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package middleware
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/crypto/blake2b"
+
+	"adapter/internal/shared/authctx"
+	"adapter/internal/shared/constants"
+	"adapter/internal/shared/crypto"
+	"adapter/internal/shared/log"
+	"adapter/internal/shared/utils"
+)
+
+const (
+	// HeaderGatewayAuthorization carries the signature a gateway adds when it
+	// forwards a request on behalf of the original sender.
+	HeaderGatewayAuthorization = "X-Gateway-Authorization"
+
+	defaultSignatureClockSkew = 5 * time.Second
+	signedHeaders             = "(created) (expires) digest"
+
+	// ONDC error reported for every signature failure
+	ondcAuthErrorType       = "CONTEXT-ERROR"
+	ondcInvalidSignatureErr = "10001"
+)
+
+// SigningKeyResolver returns the base64 ed25519 signing public key registered
+// for a subscriber's unique key ID in a registry env. An empty registry env
+// stands for the default one.
+type SigningKeyResolver interface {
+	ResolveSigningKey(ctx context.Context, registryEnv, subscriberID, uniqueKeyID string) (string, error)
+}
+
+// ONDCSignatureConfig configures ONDCSignatureAuthMiddleware.
+type ONDCSignatureConfig struct {
+	Resolver SigningKeyResolver
+	Crypto   *crypto.ONDCCrypto
+	// Realm is our subscriber ID, advertised in authentication challenges
+	Realm string
+	// RequireGatewaySignature rejects requests without X-Gateway-Authorization;
+	// otherwise the gateway signature is only verified when present
+	RequireGatewaySignature bool
+	// ClockSkew is the tolerance applied to created and expires
+	ClockSkew time.Duration
+}
+
+// ONDCSignatureAuthMiddleware verifies the ONDC signatures of a request: the
+// sender's Authorization header and, when present or required, the gateway's
+// X-Gateway-Authorization header. Each signature must cover the BLAKE-512
+// digest of the raw body, be within its created/expires window and verify
+// against the signer's key in the registry env named by the body's
+// registry_env, or the default env if it names none. Failures are answered
+// with an ONDC NACK and a challenge header. It can be mounted on any route
+// group; the verified subscribers and registry env are available through
+// authctx on the request's user context.
+func ONDCSignatureAuthMiddleware(cfg ONDCSignatureConfig) fiber.Handler {
+	if cfg.ClockSkew <= 0 {
+		cfg.ClockSkew = defaultSignatureClockSkew
+	}
+
+	return func(c *fiber.Ctx) error {
+		if message := verifyBodyDigest(c); message != "" {
+			return signatureRejected(c, fiber.HeaderWWWAuthenticate, cfg.Realm, message)
+		}
+
+		registryEnv := bodyRegistryEnv(c.Body())
+		subscriberID, message := verifySignatureHeader(c, cfg, fiber.HeaderAuthorization, registryEnv)
+		if message != "" {
+			return signatureRejected(c, fiber.HeaderWWWAuthenticate, cfg.Realm, message)
+		}
+		ctx := authctx.WithSubscriberID(c.UserContext(), subscriberID)
+		c.SetUserContext(authctx.WithRegistryEnv(ctx, registryEnv))
+
+		if c.Get(HeaderGatewayAuthorization) != "" || cfg.RequireGatewaySignature {
+			gatewayID, message := verifySignatureHeader(c, cfg, HeaderGatewayAuthorization, registryEnv)
+			if message != "" {
+				return signatureRejected(c, fiber.HeaderProxyAuthenticate, cfg.Realm, message)
+			}
+			c.SetUserContext(authctx.WithGatewayID(c.UserContext(), gatewayID))
+		}
+
+		return c.Next()
+	}
+}
+
+// verifySignatureHeader verifies one signature header and returns the signer's
+// subscriber ID, or the reason it was rejected.
+func verifySignatureHeader(c *fiber.Ctx, cfg ONDCSignatureConfig, headerName, registryEnv string) (string, string) {
+	return verifySignature(c.UserContext(), cfg, headerName, c.Get(headerName), registryEnv, c.Body())
+}
+
+// bodyRegistryEnv returns the registry_env a JSON request body targets, or an
+// empty string if it names none. Malformed bodies are left to the handler.
+func bodyRegistryEnv(body []byte) string {
+	var req struct {
+		RegistryEnv string `json:"registry_env"`
+	}
+	_ = json.Unmarshal(body, &req)
+	return req.RegistryEnv
+}
+
+// verifySignature verifies a signature header value over payload against the
+// signer's key in registryEnv and returns the signer's subscriber ID, or the
+// reason it was rejected. It is shared by the HTTP middleware and the gRPC
+// interceptors.
+func verifySignature(ctx context.Context, cfg ONDCSignatureConfig, headerName, header, registryEnv string, payload []byte) (string, string) {
+	if header == "" {
+		return "", headerName + ": " + constants.ErrAuthorizationHeaderMissing
+	}
+
+	auth, err := crypto.ParseAuthorizationHeader(header)
+	if err != nil {
+		log.Warnf(ctx, "Rejected ONDC %s header: %v", headerName, err)
+		return "", headerName + ": " + constants.ErrInvalidAuthorizationHeader
+	}
+	if auth.Algorithm != "ed25519" || (auth.Headers != "" && auth.Headers != signedHeaders) {
+		return "", headerName + ": " + constants.ErrInvalidAuthorizationHeader
+	}
+
+	now := time.Now()
+	created := time.Unix(int64(auth.Created), 0)
+	expires := time.Unix(int64(auth.Expires), 0)
+	if created.After(now.Add(cfg.ClockSkew)) || expires.Before(now.Add(-cfg.ClockSkew)) || !expires.After(created) {
+		return "", headerName + ": " + constants.ErrSignatureExpired
+	}
+
+	publicKey, err := cfg.Resolver.ResolveSigningKey(ctx, registryEnv, auth.SubscriberID, auth.UniqueKeyID)
+	if err != nil {
+		log.Warnf(ctx, "Failed to resolve signing key for %s|%s in registry env %q: %v", auth.SubscriberID, auth.UniqueKeyID, registryEnv, err)
+		return "", headerName + ": " + constants.ErrSigningKeyNotFound
+	}
+
+	valid, err := cfg.Crypto.VerifyRequest(publicKey, payload, auth.Created, auth.Expires, auth.Signature)
+	if err != nil {
+		log.Warnf(ctx, "Failed to verify signature of %s|%s: %v", auth.SubscriberID, auth.UniqueKeyID, err)
+	}
+	if err != nil || !valid {
+		return "", headerName + ": " + constants.ErrInvalidSignature
+	}
+	return auth.SubscriberID, ""
+}
+
+// verifyBodyDigest checks the optional Digest header against the BLAKE-512
+// digest of the raw body. The signatures cover the digest regardless.
+func verifyBodyDigest(c *fiber.Ctx) string {
+	header := c.Get("Digest")
+	if header == "" {
+		return ""
+	}
+	algorithm, digest, ok := strings.Cut(header, "=")
+	if !ok || !strings.EqualFold(algorithm, "BLAKE-512") {
+		return constants.ErrInvalidDigest
+	}
+	hash := blake2b.Sum512(c.Body())
+	if digest != base64.StdEncoding.EncodeToString(hash[:]) {
+		return constants.ErrInvalidDigest
+	}
+	return ""
+}
+
+func signatureRejected(c *fiber.Ctx, challengeHeader, realm, message string) error {
+	c.Set(challengeHeader, fmt.Sprintf(`Signature realm="%s",headers="%s"`, realm, signedHeaders))
+	return c.Status(fiber.StatusUnauthorized).JSON(utils.NewONDCNack(ondcAuthErrorType, ondcInvalidSignatureErr, message))
+}
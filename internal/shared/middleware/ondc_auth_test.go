@@ -0,0 +1,310 @@
+package middleware
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/crypto/blake2b"
+
+	"adapter/internal/shared/authctx"
+	"adapter/internal/shared/constants"
+	"adapter/internal/shared/crypto"
+	"adapter/internal/shared/utils"
+)
+
+// memoryResolver resolves signing keys registered under
+// "registry_env|subscriber_id|ukId", the default env being "".
+type memoryResolver map[string]string
+
+func (r memoryResolver) ResolveSigningKey(_ context.Context, registryEnv, subscriberID, uniqueKeyID string) (string, error) {
+	key, ok := r[registryEnv+"|"+subscriberID+"|"+uniqueKeyID]
+	if !ok {
+		return "", errors.New("no such key")
+	}
+	return key, nil
+}
+
+// testSigner signs requests as one subscriber key.
+type testSigner struct {
+	subscriberID, uniqueKeyID string
+	publicKey, privateKey     string
+}
+
+func newTestSigner(t *testing.T, subscriberID, uniqueKeyID string) testSigner {
+	t.Helper()
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testSigner{
+		subscriberID: subscriberID,
+		uniqueKeyID:  uniqueKeyID,
+		publicKey:    base64.StdEncoding.EncodeToString(publicKey),
+		privateKey:   base64.StdEncoding.EncodeToString(privateKey),
+	}
+}
+
+// header signs payload with a signature created at created and valid for ttl
+// seconds.
+func (s testSigner) header(t *testing.T, payload []byte, created time.Time, ttl int) string {
+	t.Helper()
+	signature, err := crypto.NewONDCCrypto().SignRequest(s.privateKey, payload, int(created.Unix()), ttl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fmt.Sprintf(`Signature keyId="%s|%s|ed25519",algorithm="ed25519",created="%d",expires="%d",headers="%s",signature="%s"`,
+		s.subscriberID, s.uniqueKeyID, created.Unix(), created.Unix()+int64(ttl), signedHeaders, signature)
+}
+
+func blakeDigest(payload []byte) string {
+	hash := blake2b.Sum512(payload)
+	return "BLAKE-512=" + base64.StdEncoding.EncodeToString(hash[:])
+}
+
+func TestONDCSignatureAuthMiddleware(t *testing.T) {
+	buyer := newTestSigner(t, "buyer.example.com", "k-buyer")
+	gateway := newTestSigner(t, "gateway.example.com", "k-gateway")
+	stranger := newTestSigner(t, "buyer.example.com", "k-buyer")
+	staging := newTestSigner(t, "staging-buyer.example.com", "k-staging")
+	resolver := memoryResolver{
+		"|buyer.example.com|k-buyer":                  buyer.publicKey,
+		"|gateway.example.com|k-gateway":              gateway.publicKey,
+		"staging|staging-buyer.example.com|k-staging": staging.publicKey,
+	}
+
+	body := []byte(`{"bap_id":"buyer.example.com"}`)
+	stagingBody := []byte(`{"bap_id":"staging-buyer.example.com","registry_env":"staging"}`)
+	now := time.Now()
+
+	tests := []struct {
+		name           string
+		body           []byte
+		headers        map[string]string
+		requireGateway bool
+		// wantError is the NACK message; empty expects the request through
+		wantError     string
+		wantChallenge string
+		wantSigner    string
+		wantGateway   string
+	}{
+		{
+			name:       "valid signature",
+			body:       body,
+			headers:    map[string]string{"Authorization": buyer.header(t, body, now, 30)},
+			wantSigner: "buyer.example.com",
+		},
+		{
+			name:       "valid signature and digest",
+			body:       body,
+			headers:    map[string]string{"Authorization": buyer.header(t, body, now, 30), "Digest": blakeDigest(body)},
+			wantSigner: "buyer.example.com",
+		},
+		{
+			name:          "missing header",
+			body:          body,
+			wantError:     "Authorization: " + constants.ErrAuthorizationHeaderMissing,
+			wantChallenge: fiber.HeaderWWWAuthenticate,
+		},
+		{
+			name:          "not a signature",
+			body:          body,
+			headers:       map[string]string{"Authorization": "Bearer abc"},
+			wantError:     "Authorization: " + constants.ErrInvalidAuthorizationHeader,
+			wantChallenge: fiber.HeaderWWWAuthenticate,
+		},
+		{
+			name:          "malformed parameter",
+			body:          body,
+			headers:       map[string]string{"Authorization": `Signature keyId="buyer.example.com|k-buyer|ed25519",created`},
+			wantError:     "Authorization: " + constants.ErrInvalidAuthorizationHeader,
+			wantChallenge: fiber.HeaderWWWAuthenticate,
+		},
+		{
+			name:          "keyId without a unique key ID",
+			body:          body,
+			headers:       map[string]string{"Authorization": strings.Replace(buyer.header(t, body, now, 30), "|k-buyer|", "||", 1)},
+			wantError:     "Authorization: " + constants.ErrInvalidAuthorizationHeader,
+			wantChallenge: fiber.HeaderWWWAuthenticate,
+		},
+		{
+			name:          "unsupported algorithm",
+			body:          body,
+			headers:       map[string]string{"Authorization": strings.Replace(buyer.header(t, body, now, 30), `algorithm="ed25519"`, `algorithm="rsa-sha256"`, 1)},
+			wantError:     "Authorization: " + constants.ErrInvalidAuthorizationHeader,
+			wantChallenge: fiber.HeaderWWWAuthenticate,
+		},
+		{
+			name:          "other signed headers",
+			body:          body,
+			headers:       map[string]string{"Authorization": strings.Replace(buyer.header(t, body, now, 30), signedHeaders, "(created) digest", 1)},
+			wantError:     "Authorization: " + constants.ErrInvalidAuthorizationHeader,
+			wantChallenge: fiber.HeaderWWWAuthenticate,
+		},
+		{
+			name:          "expired",
+			body:          body,
+			headers:       map[string]string{"Authorization": buyer.header(t, body, now.Add(-time.Minute), 30)},
+			wantError:     "Authorization: " + constants.ErrSignatureExpired,
+			wantChallenge: fiber.HeaderWWWAuthenticate,
+		},
+		{
+			name:          "created in the future",
+			body:          body,
+			headers:       map[string]string{"Authorization": buyer.header(t, body, now.Add(time.Minute), 30)},
+			wantError:     "Authorization: " + constants.ErrSignatureExpired,
+			wantChallenge: fiber.HeaderWWWAuthenticate,
+		},
+		{
+			name:       "expired within the clock skew",
+			body:       body,
+			headers:    map[string]string{"Authorization": buyer.header(t, body, now.Add(-32*time.Second), 30)},
+			wantSigner: "buyer.example.com",
+		},
+		{
+			name:          "unknown key",
+			body:          body,
+			headers:       map[string]string{"Authorization": newTestSigner(t, "buyer.example.com", "k-rotated").header(t, body, now, 30)},
+			wantError:     "Authorization: " + constants.ErrSigningKeyNotFound,
+			wantChallenge: fiber.HeaderWWWAuthenticate,
+		},
+		{
+			name:          "signed with another key",
+			body:          body,
+			headers:       map[string]string{"Authorization": stranger.header(t, body, now, 30)},
+			wantError:     "Authorization: " + constants.ErrInvalidSignature,
+			wantChallenge: fiber.HeaderWWWAuthenticate,
+		},
+		{
+			name:          "signed another body",
+			body:          body,
+			headers:       map[string]string{"Authorization": buyer.header(t, []byte(`{"bap_id":"other.example.com"}`), now, 30)},
+			wantError:     "Authorization: " + constants.ErrInvalidSignature,
+			wantChallenge: fiber.HeaderWWWAuthenticate,
+		},
+		{
+			name:          "wrong digest",
+			body:          body,
+			headers:       map[string]string{"Authorization": buyer.header(t, body, now, 30), "Digest": blakeDigest([]byte("{}"))},
+			wantError:     constants.ErrInvalidDigest,
+			wantChallenge: fiber.HeaderWWWAuthenticate,
+		},
+		{
+			name:          "digest of another algorithm",
+			body:          body,
+			headers:       map[string]string{"Authorization": buyer.header(t, body, now, 30), "Digest": "SHA-256=" + base64.StdEncoding.EncodeToString([]byte("x"))},
+			wantError:     constants.ErrInvalidDigest,
+			wantChallenge: fiber.HeaderWWWAuthenticate,
+		},
+		{
+			name:       "key of the registry env the body names",
+			body:       stagingBody,
+			headers:    map[string]string{"Authorization": staging.header(t, stagingBody, now, 30)},
+			wantSigner: "staging-buyer.example.com",
+		},
+		{
+			name:          "key of another registry env",
+			body:          body,
+			headers:       map[string]string{"Authorization": staging.header(t, body, now, 30)},
+			wantError:     "Authorization: " + constants.ErrSigningKeyNotFound,
+			wantChallenge: fiber.HeaderWWWAuthenticate,
+		},
+		{
+			name: "valid gateway signature",
+			body: body,
+			headers: map[string]string{
+				"Authorization":            buyer.header(t, body, now, 30),
+				HeaderGatewayAuthorization: gateway.header(t, body, now, 30),
+			},
+			wantSigner:  "buyer.example.com",
+			wantGateway: "gateway.example.com",
+		},
+		{
+			name: "invalid gateway signature",
+			body: body,
+			headers: map[string]string{
+				"Authorization":            buyer.header(t, body, now, 30),
+				HeaderGatewayAuthorization: gateway.header(t, body, now.Add(-time.Hour), 30),
+			},
+			wantError:     HeaderGatewayAuthorization + ": " + constants.ErrSignatureExpired,
+			wantChallenge: fiber.HeaderProxyAuthenticate,
+		},
+		{
+			name:           "missing required gateway signature",
+			body:           body,
+			headers:        map[string]string{"Authorization": buyer.header(t, body, now, 30)},
+			requireGateway: true,
+			wantError:      HeaderGatewayAuthorization + ": " + constants.ErrAuthorizationHeaderMissing,
+			wantChallenge:  fiber.HeaderProxyAuthenticate,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Post("/", ONDCSignatureAuthMiddleware(ONDCSignatureConfig{
+				Resolver:                resolver,
+				Crypto:                  crypto.NewONDCCrypto(),
+				Realm:                   "adapter.example.com",
+				RequireGatewaySignature: tt.requireGateway,
+			}), func(c *fiber.Ctx) error {
+				return c.JSON(fiber.Map{
+					"signer":  authctx.SubscriberID(c.UserContext()),
+					"gateway": authctx.GatewayID(c.UserContext()),
+				})
+			})
+
+			req := httptest.NewRequest(fiber.MethodPost, "/", strings.NewReader(string(tt.body)))
+			req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			raw, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.wantError != "" {
+				if resp.StatusCode != fiber.StatusUnauthorized {
+					t.Fatalf("status %d, want %d: %s", resp.StatusCode, fiber.StatusUnauthorized, raw)
+				}
+				var nack utils.ONDCResponse
+				if err := json.Unmarshal(raw, &nack); err != nil {
+					t.Fatal(err)
+				}
+				if nack.Error == nil || nack.Error.Message != tt.wantError {
+					t.Errorf("NACK %s, want error %q", raw, tt.wantError)
+				}
+				if challenge := resp.Header.Get(tt.wantChallenge); !strings.Contains(challenge, `realm="adapter.example.com"`) {
+					t.Errorf("%s challenge %q, want the realm", tt.wantChallenge, challenge)
+				}
+				return
+			}
+
+			if resp.StatusCode != fiber.StatusOK {
+				t.Fatalf("status %d, want %d: %s", resp.StatusCode, fiber.StatusOK, raw)
+			}
+			var got struct{ Signer, Gateway string }
+			if err := json.Unmarshal(raw, &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Signer != tt.wantSigner || got.Gateway != tt.wantGateway {
+				t.Errorf("handler saw signer %q and gateway %q, want %q and %q", got.Signer, got.Gateway, tt.wantSigner, tt.wantGateway)
+			}
+		})
+	}
+}
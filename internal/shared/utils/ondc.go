@@ -0,0 +1,35 @@
+package utils
+
+// ONDCAckStatus is the status of an ONDC acknowledgement.
+type ONDCAckStatus string
+
+const (
+	ONDCAck  ONDCAckStatus = "ACK"
+	ONDCNack ONDCAckStatus = "NACK"
+)
+
+// ONDCResponse is the acknowledgement body ONDC network participants expect
+// in reply to a protocol request.
+type ONDCResponse struct {
+	Message ONDCMessage `json:"message"`
+	Error   *ONDCError  `json:"error,omitempty"`
+}
+
+type ONDCMessage struct {
+	Ack struct {
+		Status ONDCAckStatus `json:"status"`
+	} `json:"ack"`
+}
+
+type ONDCError struct {
+	Type    string `json:"type"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// NewONDCNack builds a NACK response carrying the given error.
+func NewONDCNack(errType, code, message string) ONDCResponse {
+	response := ONDCResponse{Error: &ONDCError{Type: errType, Code: code, Message: message}}
+	response.Message.Ack.Status = ONDCNack
+	return response
+}
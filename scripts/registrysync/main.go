@@ -0,0 +1,361 @@
+// Command registrysync runs the registry sync end to end against the mock
+// registry, started in process, and a real PostgreSQL database.
+//
+// Each scenario syncs a dedicated registry env, which is cleared before and
+// after the scenario, so it can be pointed at a development database. Lookups
+// are signed with the ed25519 key in PRIVATE_KEY, which the mock registry
+// accepts for the adapter's subscriber:
+//
+//	PRIVATE_KEY=... go run ./scripts/registrysync
+//
+// It exits non-zero when a scenario fails.
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"adapter/internal/config"
+	registryDomain "adapter/internal/domain/registry_sync"
+	"adapter/internal/mockregistry"
+	catalogPorts "adapter/internal/ports/catalog_sync"
+	registryPorts "adapter/internal/ports/registry_sync"
+	"adapter/internal/shared/crypto"
+	"adapter/internal/shared/database"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const (
+	mockRegistryEnv = "mockregistry"
+
+	// The default subscriber of the adapter, registered in the fixtures
+	subscriberID = "saleor-preprod.bharatvyapaar.com"
+	uniqueKeyID  = "4a47f723-69ca-48fb-89e9-4d62c13d51b5"
+)
+
+var domains = []string{"ONDC:RET10", "ONDC:RET11"}
+
+type scenario struct {
+	name string
+	mock mockregistry.Config
+	// path of the lookup API, which selects the response format
+	path string
+	// privateKey signs the lookups
+	privateKey string
+	run        func(ctx context.Context, h *harness) error
+}
+
+type harness struct {
+	db      *gorm.DB
+	mock    *mockregistry.Server
+	service *registryDomain.ONDCService
+	sellers catalogPorts.SellerRepository
+}
+
+// noPolicies leaves new sellers without template policies.
+type noPolicies struct{}
+
+func (noPolicies) ApplyPolicyTemplatesToNewSellers(string, string, []string) (int, error) {
+	return 0, nil
+}
+
+func main() {
+	dsn := flag.String("dsn", os.Getenv("DATABASE_URL"), "PostgreSQL connection string")
+	privateKey := flag.String("private-key", os.Getenv("PRIVATE_KEY"), "base64 ed25519 private key signing the lookups")
+	fixtures := flag.String("fixtures", "internal/mockregistry/testdata", "directory holding registry.json and registry-next.json")
+	flag.Parse()
+
+	if *dsn == "" {
+		log.Fatal("a database is required: set DATABASE_URL or pass -dsn")
+	}
+	if *privateKey == "" {
+		log.Fatal("a signing key is required: set PRIVATE_KEY or pass -private-key")
+	}
+	publicKey, err := signingPublicKey(*privateKey)
+	if err != nil {
+		log.Fatal(err)
+	}
+	keys := map[string]string{subscriberID + "|" + uniqueKeyID: publicKey}
+
+	db, err := database.Init(*dsn)
+	if err != nil {
+		log.Fatal(err)
+	}
+	db = db.Session(&gorm.Session{Logger: logger.Default.LogMode(logger.Silent)})
+	if err := db.AutoMigrate(&catalogPorts.Seller{}, &catalogPorts.SellerCatalogState{}, &catalogPorts.SellerChange{}, &catalogPorts.SellerLocation{}, &registryPorts.RegistrySyncRun{}, &registryPorts.RegistrySyncRunDomain{}, &registryPorts.RegistrySyncLock{}, &registryPorts.SubscriberKey{}, &registryPorts.HeldDeactivation{}); err != nil {
+		log.Fatal(err)
+	}
+
+	generations := []string{filepath.Join(*fixtures, "registry.json"), filepath.Join(*fixtures, "registry-next.json")}
+	_, strangerKey, err := crypto.NewONDCCrypto().GenerateSigningKeys()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	scenarios := []scenario{
+		{name: "sync legacy lookup", path: "/lookup", privateKey: *privateKey, mock: mockregistry.Config{Fixtures: generations, VerifySignatures: true, Keys: keys}, run: syncGenerations},
+		{name: "sync v2 lookup", path: "/v2.0/lookup", privateKey: *privateKey, mock: mockregistry.Config{Fixtures: generations, VerifySignatures: true, Keys: keys}, run: syncGenerations},
+		{name: "retry transient failures", path: "/v2.0/lookup", privateKey: *privateKey, mock: mockregistry.Config{Fixtures: generations, FailFirst: 2, RetryAfter: time.Second}, run: retryFailures},
+		{name: "slow registry", path: "/v2.0/lookup", privateKey: *privateKey, mock: mockregistry.Config{Fixtures: generations, Latency: 200 * time.Millisecond, LatencyJitter: 100 * time.Millisecond}, run: retryFailures},
+		{name: "reject unknown signing key", path: "/v2.0/lookup", privateKey: strangerKey, mock: mockregistry.Config{Fixtures: generations, VerifySignatures: true, Keys: keys}, run: rejectSignature},
+		{name: "hold mass deactivation", path: "/v2.0/lookup", privateKey: *privateKey, mock: mockregistry.Config{Fixtures: generations, Churn: 1}, run: holdChurn},
+	}
+
+	failed := 0
+	for _, sc := range scenarios {
+		start := time.Now()
+		if err := runScenario(db, sc); err != nil {
+			failed++
+			fmt.Printf("FAIL %-28s %s: %v\n", sc.name, time.Since(start).Round(time.Millisecond), err)
+			continue
+		}
+		fmt.Printf("ok   %-28s %s\n", sc.name, time.Since(start).Round(time.Millisecond))
+	}
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+func runScenario(db *gorm.DB, sc scenario) error {
+	if err := clearRegistryEnv(db); err != nil {
+		return err
+	}
+	defer func() {
+		if err := clearRegistryEnv(db); err != nil {
+			log.Printf("failed to clear registry env %s: %v", mockRegistryEnv, err)
+		}
+	}()
+
+	mock, err := mockregistry.New(sc.mock)
+	if err != nil {
+		return err
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return err
+	}
+	go func() { _ = mock.Serve(ln) }()
+	defer func() { _ = mock.Shutdown() }()
+
+	cfg := &config.Config{
+		RegistryEnv: mockRegistryEnv,
+		Registries: map[string]config.RegistryEnvConfig{
+			mockRegistryEnv: {
+				Name:           mockRegistryEnv,
+				URL:            "http://" + ln.Addr().String() + sc.path,
+				SubscriberID:   subscriberID,
+				UniqueKeyID:    uniqueKeyID,
+				PrivateKey:     sc.privateKey,
+				Domains:        domains,
+				Country:        "IND",
+				Type:           "BPP",
+				ResponseFormat: registryDomain.LookupFormatAuto,
+			},
+		},
+		RegistryStatusLifecycle: map[string]string{
+			"SUBSCRIBED": "active",
+			"INITIATED":  "pending",
+		},
+		RegistrySyncConcurrency:         2,
+		RegistrySyncLockTTL:             time.Minute,
+		RegistrySyncBatchSize:           catalogPorts.DefaultBatchSize,
+		RegistryHTTPTimeout:             5 * time.Second,
+		RegistryRetryMaxAttempts:        4,
+		RegistryRetryBaseDelay:          50 * time.Millisecond,
+		RegistryRetryMaxDelay:           2 * time.Second,
+		RegistryBreakerFailureThreshold: 10,
+		RegistryBreakerOpenTimeout:      time.Second,
+		RegistryDeactivationMaxCount:    100,
+		RegistryDeactivationMaxPercent:  20,
+	}
+
+	sellers := catalogPorts.NewGormRepository(db)
+	runs := registryPorts.NewGormRepository(db)
+	service := registryDomain.NewONDCService(sellers, runs, runs, runs, nil, runs, noPolicies{}, cfg)
+	defer service.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	return sc.run(ctx, &harness{db: db, mock: mock, service: service, sellers: sellers})
+}
+
+// sync runs a sync job of every domain and waits for it to finish.
+func (h *harness) sync(ctx context.Context) (*registryPorts.SyncRunResponse, error) {
+	job, err := h.service.StartSyncJob(ctx, registryPorts.SyncRegistryRequest{
+		RegistryEnv:   mockRegistryEnv,
+		Domains:       domains,
+		TriggerSource: registryPorts.TriggerSourceAPI,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return h.service.WaitSyncJob(ctx, job.JobID)
+}
+
+func summaryOf(run *registryPorts.SyncRunResponse, domain string) (registryPorts.DomainSyncSummary, error) {
+	for _, summary := range run.Domains {
+		if summary.Domain == domain {
+			return summary, nil
+		}
+	}
+	return registryPorts.DomainSyncSummary{}, fmt.Errorf("run %s has no summary for %s (errors: %v)", run.RunID, domain, run.Errors)
+}
+
+func expectCompleted(run *registryPorts.SyncRunResponse) error {
+	if run.Status != string(registryPorts.SyncRunStatusCompleted) {
+		return fmt.Errorf("run %s finished %s, want %s (errors: %v)", run.RunID, run.Status, registryPorts.SyncRunStatusCompleted, run.Errors)
+	}
+	return nil
+}
+
+// syncGenerations syncs the first fixture generation twice, then the next
+// one, and checks what each run reports.
+func syncGenerations(ctx context.Context, h *harness) error {
+	first, err := h.sync(ctx)
+	if err != nil {
+		return err
+	}
+	if err := expectCompleted(first); err != nil {
+		return err
+	}
+	// The adapter's own BAP entry is outside the BPP lookup
+	for domain, want := range map[string]int{"ONDC:RET10": 4, "ONDC:RET11": 2} {
+		summary, err := summaryOf(first, domain)
+		if err != nil {
+			return err
+		}
+		if summary.NewSellers != want || summary.TotalSellersInRegistry != want {
+			return fmt.Errorf("first run: %s has %d new of %d sellers, want %d", domain, summary.NewSellers, summary.TotalSellersInRegistry, want)
+		}
+	}
+	if summary, _ := summaryOf(first, "ONDC:RET10"); summary.MultiEntrySellers != 1 {
+		return fmt.Errorf("first run: %d multi-entry sellers in ONDC:RET10, want 1", summary.MultiEntrySellers)
+	}
+
+	repeat, err := h.sync(ctx)
+	if err != nil {
+		return err
+	}
+	if err := expectCompleted(repeat); err != nil {
+		return err
+	}
+	for _, summary := range repeat.Domains {
+		if summary.NewSellers+summary.UpdatedSellers+summary.ReactivatedSellers+summary.DeactivatedSellers != 0 {
+			return fmt.Errorf("repeated run changed sellers of an unchanged registry: %+v", summary)
+		}
+	}
+
+	h.mock.Advance()
+	next, err := h.sync(ctx)
+	if err != nil {
+		return err
+	}
+	if err := expectCompleted(next); err != nil {
+		return err
+	}
+	summary, err := summaryOf(next, "ONDC:RET10")
+	if err != nil {
+		return err
+	}
+	if summary.NewSellers != 1 || summary.TotalSellersInRegistry != 4 {
+		return fmt.Errorf("next run: ONDC:RET10 has %d new of %d sellers, want 1 of 4", summary.NewSellers, summary.TotalSellersInRegistry)
+	}
+	locations, err := h.sellers.GetSellerLocations("grocer-one.example.com", "ONDC:RET10", mockRegistryEnv)
+	if err != nil {
+		return err
+	}
+	if len(locations) != 3 {
+		return fmt.Errorf("next run: grocer-one.example.com has %d locations in ONDC:RET10, want 3", len(locations))
+	}
+	return nil
+}
+
+// retryFailures checks that a run still completes when the registry fails or
+// stalls before answering.
+func retryFailures(ctx context.Context, h *harness) error {
+	run, err := h.sync(ctx)
+	if err != nil {
+		return err
+	}
+	if err := expectCompleted(run); err != nil {
+		return err
+	}
+	if stats := h.mock.Stats(); stats.Answered != len(domains) {
+		return fmt.Errorf("mock registry answered %d lookups, want %d (stats: %+v)", stats.Answered, len(domains), stats)
+	}
+	return nil
+}
+
+// rejectSignature checks that lookups signed with an unregistered key fail
+// without being retried.
+func rejectSignature(ctx context.Context, h *harness) error {
+	run, err := h.sync(ctx)
+	if err != nil {
+		return err
+	}
+	if run.Status != string(registryPorts.SyncRunStatusFailed) {
+		return fmt.Errorf("run %s finished %s, want %s", run.RunID, run.Status, registryPorts.SyncRunStatusFailed)
+	}
+	if stats := h.mock.Stats(); stats.Rejected != len(domains) {
+		return fmt.Errorf("mock registry rejected %d lookups, want %d (stats: %+v)", stats.Rejected, len(domains), stats)
+	}
+	return nil
+}
+
+// holdChurn empties the registry after a first sync and checks that the
+// deactivations are held for approval instead of applied.
+func holdChurn(ctx context.Context, h *harness) error {
+	first, err := h.sync(ctx)
+	if err != nil {
+		return err
+	}
+	if err := expectCompleted(first); err != nil {
+		return err
+	}
+
+	h.mock.Advance()
+	churned, err := h.sync(ctx)
+	if err != nil {
+		return err
+	}
+	if !churned.DeactivationsHeld {
+		return fmt.Errorf("run %s applied deactivations of a registry that dropped every seller: %+v", churned.RunID, churned.Domains)
+	}
+	for _, summary := range churned.Domains {
+		if summary.DeactivatedSellers != 0 {
+			return fmt.Errorf("run %s deactivated %d sellers of %s despite holding them", churned.RunID, summary.DeactivatedSellers, summary.Domain)
+		}
+	}
+	return nil
+}
+
+func clearRegistryEnv(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, model := range []any{&catalogPorts.Seller{}, &catalogPorts.SellerCatalogState{}, &catalogPorts.SellerChange{}, &catalogPorts.SellerLocation{}, &registryPorts.RegistrySyncRunDomain{}, &registryPorts.RegistrySyncRun{}, &registryPorts.RegistrySyncLock{}, &registryPorts.SubscriberKey{}, &registryPorts.HeldDeactivation{}} {
+			if err := tx.Where("registry_env = ?", mockRegistryEnv).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// signingPublicKey returns the base64 public key of a base64 ed25519 private
+// key.
+func signingPublicKey(privateKey string) (string, error) {
+	decoded, err := base64.StdEncoding.DecodeString(privateKey)
+	if err != nil || len(decoded) != ed25519.PrivateKeySize {
+		return "", fmt.Errorf("PRIVATE_KEY is not a base64 ed25519 private key")
+	}
+	publicKey := ed25519.PrivateKey(decoded).Public().(ed25519.PublicKey)
+	return base64.StdEncoding.EncodeToString(publicKey), nil
+}